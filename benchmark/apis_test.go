@@ -0,0 +1,381 @@
+package router
+
+import "strings"
+
+// The corpora are modeled on github.com/julienschmidt/go-http-routing-benchmark,
+// with the methods dropped since the routers only match paths.
+
+var staticAPI = []string{
+	"/",
+	"/cmd.html",
+	"/code.html",
+	"/contrib.html",
+	"/contribute.html",
+	"/debugging_with_gdb.html",
+	"/docs.html",
+	"/effective_go.html",
+	"/files.log",
+	"/gccgo_contribute.html",
+	"/gccgo_install.html",
+	"/go-logo-black.png",
+	"/go-logo-blue.png",
+	"/go-logo-white.png",
+	"/go1.1.html",
+	"/go1.2.html",
+	"/go1.html",
+	"/go1compat.html",
+	"/go_faq.html",
+	"/go_mem.html",
+	"/go_spec.html",
+	"/help.html",
+	"/ie.css",
+	"/install-source.html",
+	"/install.html",
+	"/logo-153x55.png",
+	"/Makefile",
+	"/root.html",
+	"/share.png",
+	"/sieve.gif",
+	"/tos.html",
+	"/articles/",
+	"/articles/go_command.html",
+	"/articles/index.html",
+	"/articles/wiki/",
+	"/articles/wiki/edit.html",
+	"/articles/wiki/final-noclosure.go",
+	"/articles/wiki/final-noerror.go",
+	"/articles/wiki/final-parsetemplate.go",
+	"/articles/wiki/final-template.go",
+	"/articles/wiki/final.go",
+	"/articles/wiki/get.go",
+	"/articles/wiki/http-sample.go",
+	"/articles/wiki/index.html",
+	"/articles/wiki/Makefile",
+	"/articles/wiki/notemplate.go",
+	"/articles/wiki/part1-noerror.go",
+	"/articles/wiki/part1.go",
+	"/articles/wiki/part2.go",
+	"/articles/wiki/part3-errorhandling.go",
+	"/articles/wiki/part3.go",
+	"/articles/wiki/test.bash",
+	"/articles/wiki/test_edit.good",
+	"/articles/wiki/test_Test.txt.good",
+	"/articles/wiki/test_view.good",
+	"/articles/wiki/view.html",
+	"/codewalk/",
+	"/codewalk/codewalk.css",
+	"/codewalk/codewalk.js",
+	"/codewalk/codewalk.xml",
+	"/codewalk/functions.xml",
+	"/codewalk/markov.go",
+	"/codewalk/markov.xml",
+	"/codewalk/pig.go",
+	"/codewalk/popout.png",
+	"/codewalk/run",
+	"/codewalk/sharemem.xml",
+	"/codewalk/urlpoll.go",
+	"/devel/",
+	"/devel/release.html",
+	"/devel/weekly.html",
+	"/gopher/",
+	"/gopher/appenginegopher.jpg",
+	"/gopher/frontpage.png",
+	"/gopher/gopherbw.png",
+	"/gopher/gophercolor.png",
+	"/gopher/talks.png",
+	"/gopher/pencil/",
+	"/gopher/pencil/gopherhat.jpg",
+	"/play/",
+	"/play/fib.go",
+	"/play/hello.go",
+	"/play/life.go",
+	"/play/peano.go",
+	"/play/pi.go",
+	"/play/sieve.go",
+	"/play/solitaire.go",
+	"/play/tree.go",
+	"/progs/",
+	"/progs/cgo1.go",
+	"/progs/defer.go",
+	"/progs/error.go",
+	"/progs/go1.go",
+	"/progs/gobs1.go",
+	"/progs/json1.go",
+	"/progs/run",
+	"/progs/slices.go",
+	"/progs/timeout1.go",
+	"/progs/update.bash",
+}
+
+var githubAPI = []string{
+	"/authorizations",
+	"/authorizations/{id}",
+	"/applications/{client_id}/tokens",
+	"/applications/{client_id}/tokens/{access_token}",
+	"/events",
+	"/repos/{owner}/{repo}/events",
+	"/networks/{owner}/{repo}/events",
+	"/orgs/{org}/events",
+	"/users/{user}/received_events",
+	"/users/{user}/received_events/public",
+	"/users/{user}/events",
+	"/users/{user}/events/public",
+	"/users/{user}/events/orgs/{org}",
+	"/feeds",
+	"/notifications",
+	"/repos/{owner}/{repo}/notifications",
+	"/notifications/threads/{id}",
+	"/notifications/threads/{id}/subscription",
+	"/repos/{owner}/{repo}/stargazers",
+	"/users/{user}/starred",
+	"/user/starred",
+	"/user/starred/{owner}/{repo}",
+	"/repos/{owner}/{repo}/subscribers",
+	"/users/{user}/subscriptions",
+	"/user/subscriptions",
+	"/repos/{owner}/{repo}/subscription",
+	"/user/subscriptions/{owner}/{repo}",
+	"/users/{user}/gists",
+	"/gists",
+	"/gists/{id}",
+	"/gists/{id}/star",
+	"/gists/{id}/forks",
+	"/repos/{owner}/{repo}/git/blobs",
+	"/repos/{owner}/{repo}/git/blobs/{sha}",
+	"/repos/{owner}/{repo}/git/commits",
+	"/repos/{owner}/{repo}/git/commits/{sha}",
+	"/repos/{owner}/{repo}/git/refs",
+	"/repos/{owner}/{repo}/git/tags",
+	"/repos/{owner}/{repo}/git/tags/{sha}",
+	"/repos/{owner}/{repo}/git/trees",
+	"/repos/{owner}/{repo}/git/trees/{sha}",
+	"/issues",
+	"/user/issues",
+	"/orgs/{org}/issues",
+	"/repos/{owner}/{repo}/issues",
+	"/repos/{owner}/{repo}/issues/{number}",
+	"/repos/{owner}/{repo}/assignees",
+	"/repos/{owner}/{repo}/assignees/{assignee}",
+	"/repos/{owner}/{repo}/issues/{number}/comments",
+	"/repos/{owner}/{repo}/issues/{number}/events",
+	"/repos/{owner}/{repo}/labels",
+	"/repos/{owner}/{repo}/labels/{name}",
+	"/repos/{owner}/{repo}/issues/{number}/labels",
+	"/repos/{owner}/{repo}/issues/{number}/labels/{name}",
+	"/repos/{owner}/{repo}/milestones/{number}/labels",
+	"/repos/{owner}/{repo}/milestones",
+	"/repos/{owner}/{repo}/milestones/{number}",
+	"/emojis",
+	"/gitignore/templates",
+	"/gitignore/templates/{name}",
+	"/markdown",
+	"/markdown/raw",
+	"/meta",
+	"/rate_limit",
+	"/users/{user}/orgs",
+	"/user/orgs",
+	"/orgs/{org}",
+	"/orgs/{org}/members",
+	"/orgs/{org}/members/{user}",
+	"/orgs/{org}/public_members",
+	"/orgs/{org}/public_members/{user}",
+	"/orgs/{org}/teams",
+	"/teams/{id}",
+	"/teams/{id}/members",
+	"/teams/{id}/members/{user}",
+	"/teams/{id}/repos",
+	"/teams/{id}/repos/{owner}/{repo}",
+	"/user/teams",
+	"/repos/{owner}/{repo}/pulls",
+	"/repos/{owner}/{repo}/pulls/{number}",
+	"/repos/{owner}/{repo}/pulls/{number}/commits",
+	"/repos/{owner}/{repo}/pulls/{number}/files",
+	"/repos/{owner}/{repo}/pulls/{number}/merge",
+	"/repos/{owner}/{repo}/pulls/{number}/comments",
+	"/user/repos",
+	"/users/{user}/repos",
+	"/orgs/{org}/repos",
+	"/repositories",
+	"/repos/{owner}/{repo}",
+	"/repos/{owner}/{repo}/contributors",
+	"/repos/{owner}/{repo}/languages",
+	"/repos/{owner}/{repo}/teams",
+	"/repos/{owner}/{repo}/tags",
+	"/repos/{owner}/{repo}/branches",
+	"/repos/{owner}/{repo}/branches/{branch}",
+	"/repos/{owner}/{repo}/collaborators",
+	"/repos/{owner}/{repo}/collaborators/{user}",
+	"/repos/{owner}/{repo}/comments",
+	"/repos/{owner}/{repo}/commits/{sha}/comments",
+	"/repos/{owner}/{repo}/comments/{id}",
+	"/repos/{owner}/{repo}/commits",
+	"/repos/{owner}/{repo}/commits/{sha}",
+	"/repos/{owner}/{repo}/readme",
+	"/repos/{owner}/{repo}/keys",
+	"/repos/{owner}/{repo}/keys/{id}",
+	"/repos/{owner}/{repo}/downloads",
+	"/repos/{owner}/{repo}/downloads/{id}",
+	"/repos/{owner}/{repo}/forks",
+	"/repos/{owner}/{repo}/hooks",
+	"/repos/{owner}/{repo}/hooks/{id}",
+	"/repos/{owner}/{repo}/releases",
+	"/repos/{owner}/{repo}/releases/{id}",
+	"/repos/{owner}/{repo}/releases/{id}/assets",
+	"/repos/{owner}/{repo}/stats/contributors",
+	"/repos/{owner}/{repo}/stats/commit_activity",
+	"/repos/{owner}/{repo}/stats/code_frequency",
+	"/repos/{owner}/{repo}/stats/participation",
+	"/repos/{owner}/{repo}/stats/punch_card",
+	"/repos/{owner}/{repo}/statuses/{ref}",
+	"/search/repositories",
+	"/search/code",
+	"/search/issues",
+	"/search/users",
+	"/legacy/issues/search/{owner}/{repository}/{state}/{keyword}",
+	"/legacy/repos/search/{keyword}",
+	"/legacy/user/search/{keyword}",
+	"/legacy/user/email/{email}",
+	"/users/{user}",
+	"/user",
+	"/users",
+	"/user/emails",
+	"/users/{user}/followers",
+	"/user/followers",
+	"/users/{user}/following",
+	"/user/following",
+	"/user/following/{user}",
+	"/users/{user}/following/{target_user}",
+	"/users/{user}/keys",
+	"/user/keys",
+	"/user/keys/{id}",
+}
+
+var gitlabAPI = []string{
+	"/api/v4/projects",
+	"/api/v4/projects/{id}",
+	"/api/v4/projects/{id}/issues",
+	"/api/v4/projects/{id}/issues/{issue_iid}",
+	"/api/v4/projects/{id}/issues/{issue_iid}/notes",
+	"/api/v4/projects/{id}/issues/{issue_iid}/notes/{note_id}",
+	"/api/v4/projects/{id}/merge_requests",
+	"/api/v4/projects/{id}/merge_requests/{merge_request_iid}",
+	"/api/v4/projects/{id}/merge_requests/{merge_request_iid}/changes",
+	"/api/v4/projects/{id}/merge_requests/{merge_request_iid}/commits",
+	"/api/v4/projects/{id}/merge_requests/{merge_request_iid}/notes",
+	"/api/v4/projects/{id}/merge_requests/{merge_request_iid}/approvals",
+	"/api/v4/projects/{id}/repository/branches",
+	"/api/v4/projects/{id}/repository/branches/{branch}",
+	"/api/v4/projects/{id}/repository/commits",
+	"/api/v4/projects/{id}/repository/commits/{sha}",
+	"/api/v4/projects/{id}/repository/commits/{sha}/diff",
+	"/api/v4/projects/{id}/repository/tags",
+	"/api/v4/projects/{id}/repository/tags/{tag_name}",
+	"/api/v4/projects/{id}/repository/tree",
+	"/api/v4/projects/{id}/repository/files/{file_path}",
+	"/api/v4/projects/{id}/repository/files/{file_path}/raw",
+	"/api/v4/projects/{id}/pipelines",
+	"/api/v4/projects/{id}/pipelines/{pipeline_id}",
+	"/api/v4/projects/{id}/pipelines/{pipeline_id}/jobs",
+	"/api/v4/projects/{id}/jobs",
+	"/api/v4/projects/{id}/jobs/{job_id}",
+	"/api/v4/projects/{id}/jobs/{job_id}/trace",
+	"/api/v4/projects/{id}/members",
+	"/api/v4/projects/{id}/members/{user_id}",
+	"/api/v4/projects/{id}/labels",
+	"/api/v4/projects/{id}/milestones",
+	"/api/v4/projects/{id}/milestones/{milestone_id}",
+	"/api/v4/groups",
+	"/api/v4/groups/{id}",
+	"/api/v4/groups/{id}/projects",
+	"/api/v4/groups/{id}/members",
+	"/api/v4/groups/{id}/members/{user_id}",
+	"/api/v4/users",
+	"/api/v4/users/{id}",
+	"/api/v4/users/{id}/projects",
+	"/api/v4/user",
+	"/api/v4/user/keys",
+	"/api/v4/user/keys/{key_id}",
+	"/api/v4/namespaces",
+	"/api/v4/version",
+}
+
+var parseAPI = []string{
+	"/1/classes/{className}",
+	"/1/classes/{className}/{objectId}",
+	"/1/users",
+	"/1/login",
+	"/1/users/{objectId}",
+	"/1/requestPasswordReset",
+	"/1/roles",
+	"/1/roles/{objectId}",
+	"/1/files/{fileName}",
+	"/1/events/{eventName}",
+	"/1/push",
+	"/1/installations",
+	"/1/installations/{objectId}",
+	"/1/functions",
+}
+
+var gplusAPI = []string{
+	"/people/{userId}",
+	"/people",
+	"/activities/{activityId}/people/{collection}",
+	"/people/{userId}/people/{collection}",
+	"/people/{userId}/openIdConnect",
+	"/people/{userId}/activities/{collection}",
+	"/activities/{activityId}",
+	"/activities",
+	"/activities/{activityId}/comments",
+	"/comments/{commentId}",
+	"/people/{userId}/moments/{collection}",
+	"/moments/{id}",
+}
+
+// regexAPI mixes regex constraints and trailing wildcards, with requests
+// satisfying the constraints in regexRequests.
+var regexAPI = []string{
+	"/static/{filepath:*}",
+	"/assets/{version:v[0-9]+}/{filepath:*}",
+	"/users/{id:[0-9]+}",
+	"/users/{id:[0-9]+}/posts/{post:[0-9]+}",
+	"/users/{name:[a-z]+}/profile",
+	"/archive/{year:[0-9]{4}}/{month:[0-9]{2}}",
+	"/archive/{year:[0-9]{4}}/{month:[0-9]{2}}/{slug}",
+	"/docs/{lang:en|fr|de}/{page:*}",
+	"/download/{file:*}",
+}
+
+var regexRequests = []string{
+	"/static/css/main.css",
+	"/static/js/vendor/app.min.js",
+	"/assets/v42/img/logo.png",
+	"/users/42",
+	"/users/42/posts/7",
+	"/users/gopher/profile",
+	"/archive/2024/05",
+	"/archive/2024/05/hello-world",
+	"/docs/fr/getting-started/install",
+	"/download/go1.22.linux-amd64.tar.gz",
+	"/users/gopher",
+	"/docs/it/index",
+}
+
+// requests fills the params of the patterns with a value.
+func requests(routes []string) []string {
+	var t []string
+	for _, route := range routes {
+		var b strings.Builder
+		for {
+			i := strings.IndexByte(route, '{')
+			if i == -1 {
+				break
+			}
+			b.WriteString(route[:i])
+			b.WriteString("42")
+			route = route[i+strings.IndexByte(route[i:], '}')+1:]
+		}
+		b.WriteString(route)
+		t = append(t, b.String())
+	}
+	return t
+}
@@ -1,24 +1,30 @@
 package router
 
 import (
+	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/fasthttp/router/radix"
 	ar "github.com/frankli0324/go-router"
 	"github.com/frankli0324/go-router/benchmark/router"
+	vradix "github.com/frankli0324/go-router/benchmark/router/radix"
 	"github.com/valyala/fasthttp"
 )
 
 func execute(b *testing.B, routes []string, t []string) {
 	tree := ar.NewRouter[fasthttp.RequestHandler]()
+	frozen := ar.NewRouter[fasthttp.RequestHandler]()
 	r := radix.New()
 	rr := router.New[*fasthttp.RequestHandler]()
 	var zero fasthttp.RequestHandler
 	for _, route := range routes {
 		tree.Set(route, func(ctx *fasthttp.RequestCtx) {})
+		frozen.Set(route, func(ctx *fasthttp.RequestCtx) {})
 		r.Add(route, func(ctx *fasthttp.RequestCtx) {})
 		rr.Handle(route, &zero)
 	}
+	frozen.Freeze()
 
 	b.Run("RouteHandler", func(b *testing.B) {
 		for i := 0; i < 2*b.N; i++ {
@@ -34,6 +40,13 @@ func execute(b *testing.B, routes []string, t []string) {
 			}
 		}
 	})
+	b.Run("frozen", func(b *testing.B) {
+		for i := 0; i < 2*b.N; i++ {
+			for _, request := range t {
+				frozen.GetParam(request, nil)
+			}
+		}
+	})
 	b.Run("fasthttp", func(b *testing.B) {
 		for i := 0; i < 2*b.N; i++ {
 			for _, request := range t {
@@ -91,3 +104,177 @@ func Benchmark(b *testing.B) {
 	}
 	execute(b, routes[:], t)
 }
+
+func BenchmarkLarge(b *testing.B) {
+	var routes, t []string
+	for i := 0; i < 5000; i++ {
+		routes = append(routes, fmt.Sprintf("/api/v%d/resource%d/{id}/items", i%5, i*7919%10007))
+		t = append(t, fmt.Sprintf("/api/v%d/resource%d/42/items", i%5, i*7919%10007))
+	}
+	execute(b, routes, t)
+}
+
+func BenchmarkFanOut(b *testing.B) {
+	const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+	var routes, t []string
+	for i := 0; i < len(alphabet); i++ {
+		for j := 0; j < len(alphabet); j++ {
+			// every node below /api/{version}/ has 64 literal children
+			resource := string([]byte{alphabet[i], alphabet[j]})
+			routes = append(routes, "/api/{version}/"+resource+"/{id}")
+			t = append(t, "/api/v1/"+resource+"/42")
+		}
+	}
+	execute(b, routes, t)
+}
+
+// suite benchmarks every lookup method against the radix trees, one op being
+// a pass over all the requests.
+func suite(b *testing.B, routes, requests []string) {
+	tree := ar.NewRouter[int]()
+	vendored := vradix.New[*int]()
+	r := radix.New()
+	for i, route := range routes {
+		if err := tree.Set(route, i+1); err != nil {
+			b.Fatal(err)
+		}
+		vendored.Add(route, new(int))
+		r.Add(route, func(ctx *fasthttp.RequestCtx) {})
+	}
+	for _, request := range requests {
+		if h, _ := r.Get(request, nil); (h != nil) != (tree.Get(request) != 0) {
+			b.Fatalf("routers disagree on %s", request)
+		}
+	}
+
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				tree.Get(request)
+			}
+		}
+	})
+	b.Run("GetParam", func(b *testing.B) {
+		b.ReportAllocs()
+		params := make(map[string]string)
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				tree.GetParam(request, params)
+				clear(params)
+			}
+		}
+	})
+	b.Run("GetAllMatches", func(b *testing.B) {
+		b.ReportAllocs()
+		f := func(int) bool { return true }
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				tree.GetAllMatches(request, f)
+			}
+		}
+	})
+	b.Run("radix", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				vendored.Get(request)
+			}
+		}
+	})
+	b.Run("fasthttp/Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				r.Get(request, nil)
+			}
+		}
+	})
+	b.Run("fasthttp/GetParam", func(b *testing.B) {
+		b.ReportAllocs()
+		ctx := new(fasthttp.RequestCtx)
+		for i := 0; i < b.N; i++ {
+			for _, request := range requests {
+				r.Get(request, ctx)
+				ctx.ResetUserValues()
+			}
+		}
+	})
+}
+
+func BenchmarkStatic(b *testing.B) {
+	suite(b, staticAPI, staticAPI)
+}
+
+func BenchmarkGitHub(b *testing.B) {
+	suite(b, githubAPI, requests(githubAPI))
+}
+
+func BenchmarkGitLab(b *testing.B) {
+	suite(b, gitlabAPI, requests(gitlabAPI))
+}
+
+func BenchmarkParse(b *testing.B) {
+	suite(b, parseAPI, requests(parseAPI))
+}
+
+func BenchmarkGPlus(b *testing.B) {
+	suite(b, gplusAPI, requests(gplusAPI))
+}
+
+func BenchmarkRegex(b *testing.B) {
+	suite(b, regexAPI, regexRequests)
+}
+
+func BenchmarkStartup(b *testing.B) {
+	var routes []string
+	for i := 0; i < 5000; i++ {
+		routes = append(routes, fmt.Sprintf("/api/v%d/resource%d/{id}/items", i%5, i*7919%10007))
+	}
+	codec := ar.Codec[int]{
+		Encode: func(v int) ([]byte, error) { return strconv.AppendInt(nil, int64(v), 10), nil },
+		Decode: func(b []byte) (int, error) { return strconv.Atoi(string(b)) },
+	}
+	build := func() *ar.Router[int] {
+		r := ar.NewRouter[int]()
+		r.SetCodec(codec)
+		for i, route := range routes {
+			r.Set(route, i+1)
+		}
+		return r
+	}
+	snapshot, err := build().MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Set", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			build()
+		}
+	})
+	b.Run("SetMany", func(b *testing.B) {
+		b.ReportAllocs()
+		many := make([]ar.Route[int], len(routes))
+		for i, route := range routes {
+			many[i] = ar.Route[int]{Pattern: route, Value: i + 1}
+		}
+		for i := 0; i < b.N; i++ {
+			r := ar.NewRouter[int]()
+			if err := r.SetMany(many...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("UnmarshalBinary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r := ar.NewRouter[int]()
+			r.SetCodec(codec)
+			if err := r.UnmarshalBinary(snapshot); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package router
+
+import "fmt"
+
+// Check verifies the structural invariants of the tree, which only a bug can
+// break, like after bulk loading routes. It returns an ErrInvalidTree
+// describing the first broken invariant. It only reads, so it's routine-safe.
+func (r *Router[T]) Check() error {
+	return r.tree.validate("", true)
+}
+
+// mutated panics if validating and the tree is broken, see check_on.go.
+func (r *Router[T]) mutated() {
+	if !validating {
+		return
+	}
+	if err := r.Check(); err != nil {
+		panic(err)
+	}
+}
+
+// validate checks the subtree, prefix being the expressions leading to n.
+func (n *node[T]) validate(prefix string, root bool) error {
+	prefix += n.m.string()
+	if l, ok := n.m.(literal); ok && !root {
+		if l == "" {
+			return ErrInvalidTree.With(prefix, "empty literal")
+		}
+		if n.b != l[0] {
+			return ErrInvalidTree.With(prefix, fmt.Sprintf("first byte %q of the literal is wrong", n.b))
+		}
+	} else if !ok && n.b != 0 {
+		return ErrInvalidTree.With(prefix, "first byte set on a non-literal")
+	}
+
+	lastlit, leading, seen := 0, true, [256]bool{}
+	for i, child := range n.children {
+		l, ok := child.m.(literal)
+		if leading = leading && ok; leading {
+			lastlit = i
+		}
+		if ok && l != "" {
+			if seen[l[0]] {
+				return ErrInvalidTree.With(prefix, fmt.Sprintf("literal children share the first byte %q", l[0]))
+			}
+			seen[l[0]] = true
+		}
+		if i > 0 && n.Less(i, i-1) {
+			prev := n.children[i-1]
+			// distinct expressions of the same kind are in no particular order
+			tie := typeID(prev.m) == typeID(child.m) && typeID(child.m) != 0 && !prev.m.equal(child.m)
+			if !tie {
+				return ErrInvalidTree.With(prefix, fmt.Sprintf("child '%s' is sorted after '%s'", child.m.string(), prev.m.string()))
+			}
+		}
+	}
+	if max, min := n.prios(); n.maxprio != max || n.minprio != min {
+		return ErrInvalidTree.With(prefix, fmt.Sprintf("priorities range from %d to %d, not %d to %d", min, max, n.minprio, n.maxprio))
+	}
+	if n.lastlit != lastlit {
+		return ErrInvalidTree.With(prefix, fmt.Sprintf("last leading literal is %d, not %d", lastlit, n.lastlit))
+	}
+	if n.index != nil {
+		indexed := 0
+		for b, i := range n.index {
+			if i == 0 {
+				continue
+			}
+			if indexed++; int(i) > lastlit+1 || n.children[i-1].b != byte(b) {
+				return ErrInvalidTree.With(prefix, fmt.Sprintf("index of %q is wrong", byte(b)))
+			}
+		}
+		if indexed != lastlit+1 {
+			return ErrInvalidTree.With(prefix, "leading literals are missing from the index")
+		}
+	}
+	for _, child := range n.children {
+		if err := child.validate(prefix, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+//go:build !routercheck
+
+package router
+
+const validating = false
@@ -0,0 +1,8 @@
+//go:build routercheck
+
+package router
+
+// validating makes every mutation check the invariants of the tree and panic
+// when one is broken, in builds with the routercheck tag, like
+// go test -tags routercheck.
+const validating = true
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouterCheck(t *testing.T) {
+	build := func() *Router[string] {
+		r := NewRouter[string]()
+		for _, route := range []string{"/a", "/b/{id}", "/c/{n:[0-9]+}", "/c/{rest:*}", "/{name}"} {
+			if err := r.Set(route, route); err != nil {
+				t.Fatalf("Set(%q): %v", route, err)
+			}
+		}
+		if err := r.Check(); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	corruptions := []struct {
+		name    string
+		corrupt func(root *node[string])
+		want    string
+	}{
+		{"first byte", func(root *node[string]) { root.children[0].children[0].b = 'x' }, "first byte 'x' of the literal is wrong"},
+		{"shared byte", func(root *node[string]) {
+			root.children[0].children = append(root.children[0].children, &node[string]{m: literal("ax"), b: 'a'})
+		}, "literal children share the first byte 'a'"},
+		{"empty literal", func(root *node[string]) {
+			root.children[0].children[0].children = []*node[string]{{m: literal("")}}
+		}, "empty literal"},
+		{"order", func(root *node[string]) {
+			c := root.children[0].children
+			c[0], c[len(c)-1] = c[len(c)-1], c[0]
+		}, "is sorted after"},
+		{"lastlit", func(root *node[string]) { root.children[0].lastlit = 0 }, "last leading literal is 2, not 0"},
+		{"priority", func(root *node[string]) { root.maxprio = 1 }, "priorities range from 0 to 0, not 0 to 1"},
+	}
+	for _, c := range corruptions {
+		r := build()
+		c.corrupt(&r.tree)
+		if err := r.Check(); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected %q, got %v", c.name, c.want, err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+
+	"github.com/frankli0324/go-router/internal/tree"
+)
+
+// generate writes the Go source of a package named pkg matching paths against
+// the patterns of the tree, with the same syntax and precedence as GetParam:
+//
+//	func Match(path string, ps []Param) (int, []Param)
+//
+// returns the index of the matching pattern in the generated Patterns array,
+// or -1, and appends the matched params to ps, innermost first. The values
+// are substrings of the path and the search state lives on the stack, so
+// matching doesn't allocate, unless a regex constraint is involved or ps needs
+// to grow.
+func generate(w io.Writer, pkg string, t *tree.Tree) error {
+	g := &generator{fast: t.Fast}
+	root := g.node(&t.Root)
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by routergen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(g.regexes) != 0 {
+		out.WriteString("import (\n\t\"regexp\"\n\t\"strings\"\n)\n\n")
+	} else {
+		out.WriteString("import \"strings\"\n\n")
+	}
+	out.WriteString("// Patterns are the patterns matched by Match.\nvar Patterns = [...]string{\n")
+	for _, p := range g.patterns {
+		fmt.Fprintf(&out, "\t%s,\n", strconv.Quote(p))
+	}
+	out.WriteString("}\n\n")
+	for i, re := range g.regexes {
+		fmt.Fprintf(&out, "var re%d, full%d = regexp.MustCompile(%s), regexp.MustCompile(%s)\n",
+			i, i, strconv.Quote(re.Re), strconv.Quote(re.Full))
+	}
+	out.WriteString(`
+// Param is a param matched by Match.
+type Param struct {
+	Key, Value string
+}
+
+// Match returns the index in Patterns of the pattern matching the path, or -1,
+// appending the matched params to ps, innermost first.
+func Match(path string, ps []Param) (int, []Param) {
+`)
+	if t.NoLeading {
+		out.WriteString("\tif path == \"\" {\n")
+	} else {
+		fmt.Fprintf(&out, "\tif path == \"\" || path[0] != %d {\n", t.Sep)
+	}
+	out.WriteString("\t\treturn -1, ps\n\t}\n")
+	if t.Topic {
+		out.WriteString("\tif strings.ContainsAny(path, \"+#\") {\n\t\treturn -1, ps\n\t}\n")
+	}
+	if root == "" {
+		out.WriteString("\treturn -1, ps\n}\n")
+	} else {
+		fmt.Fprintf(&out, "\tvar s search\n\treturn %s(path, ps, &s)\n}\n", root)
+	}
+	out.Write(g.funcs.Bytes())
+	fmt.Fprintf(&out, "\n// middles is the number of wildcards in the middle of patterns.\nconst middles = %d\n", g.middle)
+	out.WriteString(genHelpers)
+	if len(g.regexes) != 0 {
+		out.WriteString(genRegexHelpers)
+	}
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type generator struct {
+	fast     bool
+	patterns []string
+	regexes  []*tree.Node
+	funcs    bytes.Buffer
+	n        int
+	middle   int // wildcards in the middle of patterns
+}
+
+// node emits the function matching the children of n and returns its name,
+// or "" if n has no children.
+func (g *generator) node(n *tree.Node) string {
+	if len(n.Children) == 0 {
+		return ""
+	}
+	name := "match" + strconv.Itoa(g.n)
+	g.n++
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\nfunc %s(path string, ps []Param, s *search) (int, []Param) {\n", name)
+	lit := 0
+	for _, child := range n.Children {
+		if child.Kind != tree.Literal {
+			break
+		}
+		lit++
+	}
+	if lit != 0 {
+		// only one of the leading literals may match, they differ in the first byte
+		b.WriteString("if path != \"\" {\nswitch path[0] {\n")
+		for _, child := range n.Children[:lit] {
+			fmt.Fprintf(&b, "case %d:\n", child.Value[0])
+			g.child(&b, child)
+		}
+		b.WriteString("}\n}\n")
+	}
+	for _, child := range n.Children[lit:] {
+		g.child(&b, child)
+	}
+	b.WriteString("return -1, ps\n}\n")
+	g.funcs.Write(b.Bytes())
+	return name
+}
+
+// child emits the code trying the child and returning if it matches.
+func (g *generator) child(b *bytes.Buffer, n *tree.Node) {
+	retry, re := "", -1
+	switch n.Kind {
+	case tree.Literal:
+		fmt.Fprintf(b, "if end := %d; strings.HasPrefix(path, %s) {\n", len(n.Value), strconv.Quote(n.Value))
+	case tree.Param:
+		fmt.Fprintf(b, "for end, ok := matchParam(path, %d, %s, %t); ok; ", n.Sep, strconv.Quote(n.After), n.Greedy)
+		retry = fmt.Sprintf("end, ok = retryParam(path, %d, %s, %t, end)", n.Sep, strconv.Quote(n.After), n.Greedy)
+	case tree.Regex:
+		re = len(g.regexes)
+		g.regexes = append(g.regexes, n)
+		fmt.Fprintf(b, "for end, ok := matchRegex(re%d, full%d, path, %d, %t); ok; ", re, re, n.Sep, n.Multi)
+		retry = fmt.Sprintf("end, ok = retryRegex(re%d, full%d, path, %d, %t, end)", re, re, n.Sep, n.Multi)
+	case tree.Wildcard:
+		if len(n.Children) == 0 {
+			// the rest of the path, lazy or not
+			b.WriteString("{\nend := len(path)\n")
+			break
+		}
+		lits := ""
+		for _, child := range n.Children {
+			lits += ", " + strconv.Quote(child.Value)
+		}
+		fmt.Fprintf(b, "if bound := s.untried(%d, len(path)); bound > 0 {\ns.retrying++\n", g.middle)
+		g.middle++
+		fmt.Fprintf(b, "for end, ok := startWildcard(path, bound, %t, %t%s); ok && end < bound; ", n.Lazy, n.Assigned, lits)
+		retry = fmt.Sprintf("end, ok = retryWildcard(path, end, %t, %t%s)", n.Lazy, n.Assigned, lits)
+		defer b.WriteString("s.retrying--\n}\n")
+	case tree.Level:
+		fmt.Fprintf(b, "if end, ok := matchLevel(path, %d, %t); ok {\n", n.Sep, n.First)
+	case tree.MultiLevel:
+		fmt.Fprintf(b, "if end, ok := matchMultilevel(path, %d, %t, %t); ok {\n", n.Sep, n.First, n.Parent)
+	}
+	if retry != "" {
+		if g.fast {
+			retry = "ok = false"
+		}
+		b.WriteString(retry + " {\n")
+	}
+
+	b.WriteString("r := -1\n")
+	if next := g.node(n); next != "" {
+		fmt.Fprintf(b, "r, ps = %s(path[end:], ps, s)\n", next)
+	}
+	if n.Assigned {
+		fmt.Fprintf(b, "if r == -1 && end == len(path) {\nr = %d\n}\n", len(g.patterns))
+		g.patterns = append(g.patterns, n.Pattern)
+	}
+	b.WriteString("if r != -1 {\n")
+	if n.Kind != tree.Literal && n.Value != "" {
+		fmt.Fprintf(b, "ps = append(ps, Param{%s, path[:end]})\n", strconv.Quote(n.Value))
+	}
+	if re != -1 {
+		fmt.Fprintf(b, "ps = captureRegex(full%d, path[:end], ps)\n", re)
+	}
+	b.WriteString("return r, ps\n}\n}\n")
+}
+
+// genHelpers mirror the matchers and the search of package router.
+const genHelpers = `
+type search struct {
+	retrying int
+	tried    [middles]int // longest rest of the path tried by each wildcard, plus one
+}
+
+// untried returns the bound below which the splits of the rest of the path by
+// the wildcard in the middle of patterns weren't tried yet, and records them
+// as tried.
+func (s *search) untried(wildcard, rest int) int {
+	if s.retrying == 0 {
+		return rest + 1
+	}
+	tried := s.tried[wildcard] - 1
+	if rest > tried {
+		s.tried[wildcard] = rest + 1
+	}
+	return rest - tried
+}
+
+func startWildcard(s string, bound int, lazy, self bool, lits ...string) (int, bool) {
+	if lazy {
+		return 0, true
+	}
+	if bound > len(s) {
+		return len(s), true
+	}
+	return retryWildcard(s, bound, lazy, self, lits...)
+}
+
+func retryWildcard(s string, end int, lazy, self bool, lits ...string) (int, bool) {
+	next := -1
+	for _, l := range lits {
+		i := -1
+		if lazy {
+			if end < len(s) {
+				if j := strings.Index(s[end+1:], l); j != -1 {
+					i = end + 1 + j
+				}
+			}
+		} else if end > 0 {
+			limit := end - 1 + len(l)
+			if limit > len(s) {
+				limit = len(s)
+			}
+			i = strings.LastIndex(s[:limit], l)
+		}
+		if next == -1 || i != -1 && (i < next) == lazy {
+			next = i
+		}
+	}
+	if next == -1 && lazy && self && end < len(s) {
+		next = len(s)
+	}
+	return next, next != -1
+}
+
+func segment(s string, sep byte) int {
+	if i := strings.IndexByte(s, sep); i != -1 {
+		return i
+	}
+	return len(s)
+}
+
+func matchParam(s string, sep byte, after string, greedy bool) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	if after != "" {
+		return retryParam(s, sep, after, greedy, 0)
+	}
+	return segment(s, sep), true
+}
+
+func retryParam(s string, sep byte, after string, greedy bool, end int) (int, bool) {
+	if after == "" {
+		return 0, false
+	}
+	i := segment(s, sep)
+	if greedy {
+		if end != 0 {
+			i = end - 1 + len(after)
+		}
+		if i < 1 {
+			return 0, false
+		}
+		j := strings.LastIndex(s[1:i], after)
+		return 1 + j, j != -1
+	}
+	if end+1 > i {
+		return 0, false
+	}
+	j := strings.Index(s[end+1:i], after)
+	return end + 1 + j, j != -1
+}
+
+func matchLevel(s string, sep byte, first bool) (int, bool) {
+	if first && s != "" && s[0] == '$' {
+		return 0, false
+	}
+	return segment(s, sep), true
+}
+
+func matchMultilevel(s string, sep byte, first, parent bool) (int, bool) {
+	if first && s != "" && s[0] == '$' {
+		return 0, false
+	}
+	if parent && s != "" && s[0] != sep {
+		return 0, false
+	}
+	return len(s), true
+}
+`
+
+const genRegexHelpers = `
+func matchRegex(re, full *regexp.Regexp, s string, sep byte, multi bool) (int, bool) {
+	if !multi {
+		s = s[:segment(s, sep)]
+	}
+	if m := re.FindStringIndex(s); m != nil && m[1] != 0 {
+		return m[1], true
+	}
+	// a lazy expression prefers the empty match, take the longest instead
+	return retryRegex(re, full, s, sep, multi, 0)
+}
+
+func retryRegex(re, full *regexp.Regexp, s string, sep byte, multi bool, end int) (int, bool) {
+	if !multi {
+		s = s[:segment(s, sep)]
+	}
+	first := 0
+	if m := re.FindStringIndex(s); m != nil {
+		first = m[1]
+	}
+	i := len(s)
+	if end != first {
+		i = end - 1
+	}
+	for ; i > 0; i-- {
+		if i != first && full.MatchString(s[:i]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func captureRegex(full *regexp.Regexp, s string, ps []Param) []Param {
+	names := full.SubexpNames()
+	if len(names) == 1 {
+		return ps
+	}
+	m := full.FindStringSubmatchIndex(s)
+	for i := 1; i < len(names) && m != nil; i++ {
+		if names[i] != "" && m[2*i] >= 0 {
+			ps = append(ps, Param{names[i], s[m[2*i]:m[2*i+1]]})
+		}
+	}
+	return ps
+}
+`
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	router "github.com/frankli0324/go-router"
+	"github.com/frankli0324/go-router/internal/tree"
+)
+
+const genMain = `package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func main() {
+	type result struct {
+		Pattern string
+		Params  map[string]string
+		Allocs  float64
+	}
+	var results []result
+	buf := make([]Param, 0, 8)
+	for _, path := range strings.Split(os.Args[1], "\n") {
+		res := result{Params: map[string]string{}}
+		i, ps := Match(path, nil)
+		if i != -1 {
+			res.Pattern = Patterns[i]
+		}
+		for _, p := range ps {
+			res.Params[p.Key] = p.Value
+		}
+		res.Allocs = testing.AllocsPerRun(10, func() {
+			Match(path, buf[:0])
+		})
+		results = append(results, res)
+	}
+	json.NewEncoder(os.Stdout).Encode(results)
+}
+`
+
+// TestGenerate cross-checks the generated matchers against the runtime router.
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles generated code")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+
+	for name, tc := range map[string]struct {
+		opts    []router.Option
+		routes  []string
+		paths   []string
+		noAlloc []string // paths matched without allocating, no regex being involved
+	}{
+		"default": {
+			routes: []string{
+				"/", "/hi", "/contact/", "/co", "/c", "/α", "/β",
+				"/hello/test", "/hello/{name}", "/user_{name}/about",
+				"/files/{name}.tar.gz", "/greedy/{file+}.{ext}", "/v{major}.{minor}",
+				"/regex/{c1:big_alt|alt|small_alt}/{rest:*}",
+				"/regex/{c2:(?<named>extra)_alt}/{rest:*}",
+				"/regex/{path:*}", "/alt/{x:a|ab}c",
+				"/{repo:*}/-/blob/{ref}", "/lazy/{path:*?}/raw",
+				"/m/{a:*}/{b:*}/{c:*}/z", "/n/{p:*?}/{q:*?}/w", "/z/{n:0*?}",
+			},
+			paths: []string{
+				"/", "/hi", "/contact", "/contact/", "/co", "/cx", "/α", "/β", "/hello/test",
+				"/hello/tes", "/hello/test/", "/user_gopher/about", "/files/a.tar.gz.tar.gz",
+				"/greedy/a.b.c", "/v1.2", "/regex/alt/x", "/regex/extra_alt/x/y", "/regex/other",
+				"/alt/abc", "/a/b/-/blob/main", "/lazy/a/b/raw", "/m/a/b/c/d/z", "/m/a/b/z/x",
+				"/n/a/b/c/w", "/n/a/w/b/w", "/n/a/w", "/z/00", "invalid", "",
+			},
+			noAlloc: []string{
+				"/hello/tes", "/user_gopher/about", "/greedy/a.b.c", "/a/b/-/blob/main",
+				"/lazy/a/b/raw", "/m/a/b/c/d/z", "/m/a/b/z/x", "/n/a/b/c/w", "/n/a/w/b/w",
+			},
+		},
+		"fast": {
+			opts:   []router.Option{router.WithoutBacktracking()},
+			routes: []string{"/files/{name}.tar.gz", "/alt/{x:a|ab}c", "/{path:*}"},
+			paths:  []string{"/files/a.tar.gz", "/files/a.tar.gz.tar.gz", "/alt/abc", "/alt/ac"},
+		},
+		"topic": {
+			opts:   []router.Option{router.WithTopicFilters()},
+			routes: []string{"sport/tennis/player1", "sport/#", "sport/+", "+/+", "/+", "#", "$SYS/#"},
+			paths:  []string{"sport", "sport/", "sport/tennis/player1", "/finance", "$SYS/x", "sport/+"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := router.NewRouter[string](tc.opts...)
+			for _, route := range tc.routes {
+				if err := r.Set(route, route); err != nil {
+					t.Fatalf("Set(%q): %v", route, err)
+				}
+			}
+			dir := t.TempDir()
+			f, err := os.Create(filepath.Join(dir, "routes_gen.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rt, _ := tree.Of(r)
+			if err := generate(f, "main", rt); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			f.Close()
+			os.WriteFile(filepath.Join(dir, "main.go"), []byte(genMain), 0o644)
+			os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gentest\n\ngo 1.18\n"), 0o644)
+
+			cmd := exec.Command(gobin, "run", ".", strings.Join(tc.paths, "\n"))
+			cmd.Dir = dir
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("running generated code: %v\n%s", err, err.(*exec.ExitError).Stderr)
+			}
+			var got []struct {
+				Pattern string
+				Params  map[string]string
+				Allocs  float64
+			}
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			for i, path := range tc.paths {
+				params := make(map[string]string)
+				want := r.GetParam(path, params)
+				if got[i].Pattern != want || !reflect.DeepEqual(got[i].Params, params) {
+					t.Errorf("generated matcher matched '%s' with %q %v, want %q %v", path, got[i].Pattern, got[i].Params, want, params)
+				}
+			}
+			for _, path := range tc.noAlloc {
+				for i := range tc.paths {
+					if tc.paths[i] == path && got[i].Allocs != 0 {
+						t.Errorf("generated matcher allocated %v times matching '%s'", got[i].Allocs, path)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Command routergen generates a static matcher for a list of route patterns,
+// with the same syntax and precedence as router.Router. The patterns are read
+// one per line, blank lines are ignored. Typical use:
+//
+//	//go:generate go run github.com/frankli0324/go-router/cmd/routergen -pkg routes -o routes_gen.go routes.txt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	router "github.com/frankli0324/go-router"
+	"github.com/frankli0324/go-router/internal/tree"
+)
+
+func main() {
+	var (
+		out       = flag.String("o", "", "output file, stdout if empty")
+		pkg       = flag.String("pkg", "routes", "package name of the generated file")
+		sep       = flag.String("sep", "/", "segment separator")
+		noLeading = flag.Bool("no-leading", false, "allow patterns not starting with the separator")
+		topic     = flag.Bool("topic", false, "use MQTT topic filter syntax")
+		fast      = flag.Bool("fast", false, "disable backtracking")
+		multi     = flag.Bool("multi-segment-regex", false, "let regexes match across separators")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: routergen [flags] [patterns file]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if len(*sep) != 1 || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var opts []router.Option
+	opts = append(opts, router.WithSeparator((*sep)[0]))
+	if *noLeading {
+		opts = append(opts, router.WithoutLeadingSeparator())
+	}
+	if *topic {
+		opts = append(opts, router.WithTopicFilters())
+	}
+	if *fast {
+		opts = append(opts, router.WithoutBacktracking())
+	}
+	if *multi {
+		opts = append(opts, router.WithMultiSegmentRegex())
+	}
+	if err := run(flag.Arg(0), *out, *pkg, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "routergen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, pkg string, opts []router.Option) error {
+	var r io.Reader = os.Stdin
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var routes []router.Route[struct{}]
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pattern := strings.TrimSpace(scanner.Text()); pattern != "" {
+			routes = append(routes, router.Route[struct{}]{Pattern: pattern})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	rt := router.NewRouter[struct{}](opts...)
+	if err := rt.SetMany(routes...); err != nil {
+		return err
+	}
+	t, _ := tree.Of(rt)
+	var buf bytes.Buffer
+	if err := generate(&buf, pkg, t); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,90 @@
+package router
+
+import "sort"
+
+// ChangeKind tells what a Change is about.
+type ChangeKind int
+
+const (
+	// Added is a pattern only registered in the second router.
+	Added ChangeKind = iota
+	// Removed is a pattern only registered in the first router.
+	Removed
+	// Modified is a pattern registered in both routers with another value,
+	// name or priority.
+	Modified
+	// Rerouted is a path the routers route to different patterns.
+	Rerouted
+)
+
+// Change is a difference between two routers. Old and New are the routes of
+// the first and the second router, or the zero Route where there is none.
+type Change[T any] struct {
+	Kind     ChangeKind
+	Old, New Route[T]
+	Path     string // the path, if Rerouted
+}
+
+// examplesPerPattern bounds the example paths built from every pattern.
+const examplesPerPattern = 8
+
+// Diff lists the patterns added, removed and modified from a to b, then the
+// paths they route differently, in order. The paths are examples built from
+// the patterns of both routers, so that a pattern shadowing another one in
+// b shows up with a path it takes over. The values are compared with eq,
+// or not at all if it's nil. It's routine-safe.
+func Diff[T any](a, b *Router[T], eq func(T, T) bool) []Change[T] {
+	var changes []Change[T]
+	ra, rb := a.Routes(), b.Routes()
+	inA, inB := make(map[string]Route[T], len(ra)), make(map[string]Route[T], len(rb))
+	for _, route := range ra {
+		inA[route.Pattern] = route
+	}
+	for _, route := range rb {
+		inB[route.Pattern] = route
+	}
+	for _, x := range ra {
+		if y, ok := inB[x.Pattern]; !ok {
+			changes = append(changes, Change[T]{Kind: Removed, Old: x})
+		} else if x.Name != y.Name || x.Priority != y.Priority || eq != nil && !eq(x.Value, y.Value) {
+			changes = append(changes, Change[T]{Kind: Modified, Old: x, New: y})
+		}
+	}
+	for _, y := range rb {
+		if _, ok := inA[y.Pattern]; !ok {
+			changes = append(changes, Change[T]{Kind: Added, New: y})
+		}
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, r := range [...]struct {
+		c      *config
+		routes []Route[T]
+	}{{&a.config, ra}, {&b.config, rb}} {
+		for _, route := range r.routes {
+			for _, path := range r.c.examples(route.Pattern, examplesPerPattern) {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		na, nb := a.match(path), b.match(path)
+		if na == nil && nb == nil || na != nil && nb != nil && na.pattern == nb.pattern {
+			continue
+		}
+		change := Change[T]{Kind: Rerouted, Path: path}
+		if na != nil {
+			change.Old = na.route()
+		}
+		if nb != nil {
+			change.New = nb.route()
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -1,6 +1,9 @@
 package router
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type err struct {
 	string
@@ -16,9 +19,32 @@ func (e *err) With(params ...interface{}) *err {
 }
 
 var (
-	ErrInvalidPath      = &err{"path must begin with '/' in path '%s'", nil}
+	ErrInvalidPath      = &err{"path must begin with '%c' in path '%s'", nil}
+	ErrEmptyPath        = &err{"path must not be empty", nil}
 	ErrExprConflict     = &err{"path '%s' conflicts with existing wildcard or param '%s'", nil}
 	ErrConflict         = &err{"a handler is already registered for path '%s'", nil}
+	ErrFrozen           = &err{"the router is frozen, cannot register path '%s'", nil}
 	ErrExpr             = &err{"invalid expression '%s': '%s'", nil}
+	ErrPriorityConflict = &err{"path '%s' may overlap with a route of the same priority %d under '%s'", nil}
 	ErrWildcardNotAtEnd = &err{"wildcard routes are only allowed at the end of the path in path '%s'", nil}
+	ErrInvalidTree      = &err{"invalid tree under '%s': %s", nil}
+	ErrSnapshot         = &err{"invalid snapshot: %s", nil}
+	ErrNotFound         = &err{"no handler is registered for path '%s'", nil}
+	ErrUnreachable      = &err{"no example path is routed to '%s'", nil}
 )
+
+// Errors gathers the errors of several routes, as returned by SetMany.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap lets errors.Is and errors.As look into every error.
+func (e Errors) Unwrap() []error {
+	return e
+}
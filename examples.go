@@ -0,0 +1,182 @@
+package router
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// maxExamples bounds the paths tried for a pattern by Examples.
+const maxExamples = 64
+
+// Examples returns paths routed to the registered pattern, the simplest
+// first. They are built from sample values of its params, regexes and
+// wildcards, checked against the patterns taking precedence. It returns
+// ErrNotFound if the pattern isn't registered, and ErrUnreachable if no
+// sample path is routed to it. It's routine-safe.
+func (r *Router[T]) Examples(pattern string) ([]string, error) {
+	n := r.tree.find(pattern, &r.config)
+	if n == nil || !n.assigned {
+		return nil, ErrNotFound.With(pattern)
+	}
+	var paths []string
+	for _, path := range r.examples(pattern, maxExamples) {
+		if r.match(path) == n {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, ErrUnreachable.With(pattern)
+	}
+	return paths, nil
+}
+
+// AllExamples returns the paths Examples finds for every registered pattern,
+// along with an Errors listing the unreachable ones. It's routine-safe.
+func (r *Router[T]) AllExamples() (map[string][]string, error) {
+	examples := make(map[string][]string)
+	var errs Errors
+	for _, route := range r.Routes() {
+		paths, err := r.Examples(route.Pattern)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		examples[route.Pattern] = paths
+	}
+	if len(errs) != 0 {
+		return examples, errs
+	}
+	return examples, nil
+}
+
+// examples returns up to limit paths the matchers of the pattern accept,
+// trying a few values for every param, regex and wildcard. The paths aren't
+// necessarily routed to the pattern, another one may take precedence.
+func (c *config) examples(pattern string, limit int) []string {
+	ms, err := c.parse(pattern)
+	if err != nil {
+		return nil
+	}
+	paths := []string{""}
+	for _, m := range ms {
+		values := c.values(m)
+		var next []string
+		for _, p := range paths {
+			for _, v := range values {
+				if len(next) < limit && accepts(m, p+v, len(p)) {
+					next = append(next, p+v)
+				}
+			}
+		}
+		paths = next
+	}
+	return paths
+}
+
+// accepts reports whether m may match path[start:end], end being any later
+// position since the rest of the path is unknown yet. It only filters out
+// values the matcher can't produce, like a regex sample crossing the separator.
+func accepts(m matcher, path string, start int) bool {
+	switch m := m.(type) {
+	case param:
+		return !strings.Contains(path[start:], string(m.sep)) && !(m.after != "" && strings.Contains(path[start:], m.after))
+	case regex:
+		return m.full.MatchString(path[start:]) && (m.multi || !strings.Contains(path[start:], string(m.sep)))
+	}
+	return true
+}
+
+// values returns candidate values for the matcher.
+func (c *config) values(m matcher) []string {
+	sep := string(c.sep)
+	switch m := m.(type) {
+	case literal:
+		return []string{string(m)}
+	case param:
+		return []string{"x", "1", "y2", "z_"}
+	case regex:
+		if v, ok := sample(m.expr); ok {
+			return []string{v}
+		}
+		return nil
+	case wildcard:
+		return []string{"x", "", "x" + sep + "y"}
+	case level:
+		return []string{"x"}
+	case multilevel:
+		if m.parent {
+			return []string{"", sep + "x"}
+		}
+		return []string{"x", ""}
+	}
+	return nil
+}
+
+// sample returns a short string the expression matches entirely.
+func sample(expr string) (string, bool) {
+	re, err := syntax.Parse(strings.ReplaceAll(expr, "(?<", "(?P<"), syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	if !sampleTo(&b, re.Simplify()) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func sampleTo(b *strings.Builder, re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return false
+		}
+		r := re.Rune[0]
+		// prefer a letter or a digit to the first rune of the class
+	prefer:
+		for _, want := range "a0A" {
+			for i := 0; i < len(re.Rune); i += 2 {
+				if re.Rune[i] <= want && want <= re.Rune[i+1] {
+					r = want
+					break prefer
+				}
+			}
+		}
+		b.WriteRune(r)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte('a')
+	case syntax.OpCapture:
+		return sampleTo(b, re.Sub[0])
+	case syntax.OpPlus:
+		return sampleTo(b, re.Sub[0])
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			if !sampleTo(b, re.Sub[0]) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !sampleTo(b, sub) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			var alt strings.Builder
+			if sampleTo(&alt, sub) {
+				b.WriteString(alt.String())
+				return true
+			}
+		}
+		return false
+	case syntax.OpNoMatch:
+		return false
+	}
+	// empty matches, anchors, stars and quests match the empty string
+	return true
+}
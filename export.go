@@ -0,0 +1,47 @@
+package router
+
+import "github.com/frankli0324/go-router/internal/tree"
+
+func init() {
+	tree.Of = func(r any) (*tree.Tree, bool) {
+		if e, ok := r.(exporter); ok {
+			return e.export(), true
+		}
+		return nil, false
+	}
+}
+
+// exporter is implemented by routers of any value type.
+type exporter interface {
+	export() *tree.Tree
+}
+
+// export copies the routing tree for tree.Of. It's not routine-safe.
+func (r *Router[T]) export() *tree.Tree {
+	t := &tree.Tree{Sep: r.sep, NoLeading: r.noLeading, Topic: r.topic, Fast: r.fast}
+	r.tree.export(&t.Root)
+	return t
+}
+
+func (n *node[T]) export(e *tree.Node) {
+	e.Pattern, e.Assigned = n.pattern, n.assigned
+	switch m := n.m.(type) {
+	case literal:
+		e.Kind, e.Value = tree.Literal, string(m)
+	case param:
+		e.Kind, e.Value, e.After, e.Sep, e.Greedy = tree.Param, m.key, m.after, m.sep, m.greedy
+	case regex:
+		e.Kind, e.Value, e.Re, e.Full, e.Sep, e.Multi = tree.Regex, m.key, m.re.String(), m.full.String(), m.sep, m.multi
+	case wildcard:
+		e.Kind, e.Value, e.Lazy = tree.Wildcard, m.key, m.lazy
+	case level:
+		e.Kind, e.Sep, e.First = tree.Level, m.sep, m.first
+	case multilevel:
+		e.Kind, e.Sep, e.First, e.Parent = tree.MultiLevel, m.sep, m.first, m.parent
+	}
+	e.Children = make([]*tree.Node, len(n.children))
+	for i, child := range n.children {
+		e.Children[i] = new(tree.Node)
+		child.export(e.Children[i])
+	}
+}
@@ -2,19 +2,20 @@ package router
 
 import (
 	"regexp"
+	"regexp/syntax"
 	"strings"
 )
 
 // seg returns the index where the segment ends from the given path
-func seg(path string) string {
+func seg(path string, sep byte) string {
 	end := 0
-	for end < len(path) && path[end] != '/' {
+	for end < len(path) && path[end] != sep {
 		end++
 	}
 	return path[:end]
 }
 
-func nextNonLiteral(path string) (matcher, int, error) {
+func nextNonLiteral(path string, cfg *config) (matcher, int, error) {
 	extend := -1
 	keys := 0
 
@@ -23,6 +24,10 @@ func nextNonLiteral(path string) (matcher, int, error) {
 	for i, c := range []byte(path) {
 		switch c {
 		case '}':
+			if keys > 0 { // closes a brace of the regex, like in [0-9]{4}
+				keys--
+				continue
+			}
 			if len(path) > i+1 && path[i+1] == '{' {
 				return nil, 0, ErrExpr.With("{"+path, "the expressions must be separated by at least 1 char")
 			}
@@ -41,15 +46,33 @@ func nextNonLiteral(path string) (matcher, int, error) {
 			}
 			switch ext {
 			case "":
-				s := seg(path[i+1:])
-				return param{key: key, after: s}, len(s) + i + 2, nil
+				s := seg(path[i+1:], cfg.sep)
+				if j := strings.IndexByte(s, '{'); j != -1 {
+					s = s[:j]
+				}
+				greedy := key[len(key)-1] == '+'
+				if greedy {
+					if key = key[:len(key)-1]; key == "" {
+						return nil, 0, ErrExpr.With("{"+path, "wildcards must be named with a non-empty name")
+					}
+				}
+				// the suffix is also parsed as the following literal
+				return param{key: key, after: s, sep: cfg.sep, greedy: greedy}, i + 2, nil
 			case "*":
-				return wildcard(key), i + 2, nil
+				return wildcard{key: key}, i + 2, nil
+			case "*?":
+				return wildcard{key: key, lazy: true}, i + 2, nil
 			default:
-				return regex{key, regexp.MustCompile(ext)}, i + 2, nil
+				re, err := newRegex(key, ext, cfg.sep, cfg.multi)
+				if err != nil {
+					return nil, 0, ErrExpr.With("{"+path, err.Error())
+				}
+				return re, i + 2, nil
 			}
 		case ':':
-			extend = i
+			if extend == -1 {
+				extend = i
+			}
 		case '{':
 			if extend == -1 && keys == 0 {
 				return nil, 0, ErrExpr.With(path, "the char '{' is not allowed in the param name")
@@ -58,10 +81,24 @@ func nextNonLiteral(path string) (matcher, int, error) {
 			keys++
 		}
 	}
-	return nil, 0, nil
+	return nil, 0, ErrExpr.With("{"+path, "missing closing '}'")
+}
+
+func newRegex(key, expr string, sep byte, multi bool) (regex, error) {
+	// (?<name>) is only understood by regexp since go1.22
+	e := strings.ReplaceAll(expr, "(?<", "(?P<")
+	if _, err := syntax.Parse(e, syntax.Perl); err != nil {
+		return regex{}, err // valid only once wrapped, like ")|("
+	}
+	re, err := regexp.Compile("^(?:" + e + ")")
+	if err != nil {
+		return regex{}, err
+	}
+	full := regexp.MustCompile("^(?:" + e + ")$")
+	return regex{key: key, expr: expr, re: re, full: full, sep: sep, multi: multi}, nil
 }
 
-func next(path string) (matcher, int, error) {
+func next(path string, cfg *config) (matcher, int, error) {
 	i := strings.IndexByte(path, '{')
 	if i == -1 {
 		return literal(path), len(path), nil
@@ -69,5 +106,34 @@ func next(path string) (matcher, int, error) {
 	if i != 0 {
 		return literal(path[:i]), i, nil
 	}
-	return nextNonLiteral(path)
+	return nextNonLiteral(path, cfg)
+}
+
+// nextTopic parses MQTT topic filters, where '+' and '#' must occupy entire levels.
+func nextTopic(path string, sep byte, start bool) (matcher, int, error) {
+	switch {
+	case path[0] == '+':
+		if len(path) > 1 && path[1] != sep {
+			return nil, 0, ErrExpr.With(path, "'+' must occupy an entire level")
+		}
+		return level{sep: sep, first: start}, 1, nil
+	case path[0] == '#':
+		if len(path) > 1 {
+			return nil, 0, ErrExpr.With(path, "'#' must be the last character")
+		}
+		return multilevel{sep: sep, first: start}, 1, nil
+	case path[0] == sep && len(path) == 2 && path[1] == '#':
+		return multilevel{sep: sep, first: start, parent: true}, 2, nil
+	}
+	i := strings.IndexAny(path, "+#")
+	if i == -1 {
+		return literal(path), len(path), nil
+	}
+	if path[i-1] != sep {
+		return nil, 0, ErrExpr.With(path, "wildcards must occupy an entire level")
+	}
+	if path[i] == '#' && i > 1 {
+		i-- // leave the separator to '#', which also matches the parent level
+	}
+	return literal(path[:i]), i, nil
 }
@@ -0,0 +1,226 @@
+package router
+
+import "strings"
+
+// flat is a read-only copy of the tree laid out in contiguous arrays, built
+// by Freeze. The children of a node are stored next to each other, literal
+// children first, and are found by their first byte: scanning the keys of a
+// few, through a jump table of 256 entries from indexMin on, like the tree.
+type flat[T any] struct {
+	table
+	values []T
+}
+
+type table struct {
+	nodes []fnode
+	keys  string   // first byte of the literal of every node, parallel to nodes
+	index []uint16 // jump tables, 256 entries each, to the leading literal children plus one
+	ms    []matcher
+}
+
+type fnode struct {
+	lit   string // slice of a pool holding every literal, if m is -1
+	m     int32  // index in ms, -1 for literals
+	value int32  // index in values, -1 if unassigned
+	prio  int    // priority of the route, if assigned
+	max   int    // highest priority in the subtree
+	min   int    // lowest priority in the subtree
+	first uint32 // children are nodes[first : first+n]
+	n     uint32
+	nlit  uint32 // leading literal children, replacing lastlit
+	index int32  // offset of the jump table in index, -1 to scan the keys
+}
+
+func newFlat[T any](root *node[T]) *flat[T] {
+	f := &flat[T]{table: table{nodes: make([]fnode, 1)}}
+	keys := []byte{0}
+	var build func(i int, n *node[T])
+	build = func(i int, n *node[T]) {
+		fn := fnode{m: -1, value: -1, index: -1, first: uint32(len(f.nodes)), n: uint32(len(n.children)), max: n.maxprio, min: n.minprio}
+		if l, ok := n.m.(literal); ok || n.m == nil {
+			fn.lit = string(l)
+		} else {
+			fn.m = int32(len(f.ms))
+			f.ms = append(f.ms, n.m)
+		}
+		if n.assigned {
+			fn.value, fn.prio = int32(len(f.values)), n.priority
+			f.values = append(f.values, n.handler)
+		}
+		for _, child := range n.children {
+			if _, ok := child.m.(literal); !ok {
+				break
+			}
+			fn.nlit++
+		}
+		if fn.nlit >= indexMin {
+			fn.index = int32(len(f.index))
+			f.index = append(f.index, make([]uint16, 256)...)
+			for j, child := range n.children[:fn.nlit] {
+				if child.b == 0 {
+					// empty literal or starting with the NUL byte
+					f.index, fn.index = f.index[:fn.index], -1
+					break
+				}
+				f.index[int(fn.index)+int(child.b)] = uint16(j + 1)
+			}
+		}
+		f.nodes[i] = fn
+		f.nodes = append(f.nodes, make([]fnode, len(n.children))...)
+		for _, child := range n.children {
+			keys = append(keys, child.b)
+		}
+		for j, child := range n.children {
+			build(int(fn.first)+j, child)
+		}
+	}
+	build(0, root)
+	f.keys = string(keys)
+
+	var pool strings.Builder
+	for i := range f.nodes {
+		pool.WriteString(f.nodes[i].lit)
+	}
+	s, off := pool.String(), 0
+	for i := range f.nodes {
+		l := len(f.nodes[i].lit)
+		f.nodes[i].lit = s[off : off+l]
+		off += l
+	}
+	return f
+}
+
+// get is node.get over the flat layout, returning an index in values or -1.
+func (f *table) get(path string, params map[string]string, backtrack bool) int32 {
+	best := int32(-1)
+	if root := &f.nodes[0]; root.max == root.min {
+		best = f.lookup(0, path, params, &search{backtrack: backtrack})
+	} else if best = f.lookup(0, path, nil, &search{backtrack: backtrack}); best != -1 && params != nil {
+		f.lookup(0, path, params, &search{backtrack: backtrack, target: best})
+	}
+	if best == -1 {
+		return -1
+	}
+	return f.nodes[best].value
+}
+
+// lookup is node.lookup over the flat layout, returning an index in nodes or
+// -1.
+func (f *table) lookup(i uint32, path string, params map[string]string, s *search) int32 {
+	n, best := &f.nodes[i], int32(-1)
+	first, last, j := n.first, n.first+n.n, n.first+n.nlit
+	if path != "" && n.nlit != 0 {
+		// only one of the leading literals may match, find it by its first byte
+		k := -1
+		if n.index != -1 {
+			k = int(f.index[int(n.index)+int(path[0])]) - 1
+		} else {
+			keys := f.keys[first:j]
+			for l := 0; l < len(keys); l++ {
+				if keys[l] == path[0] {
+					k = l
+					break
+				}
+			}
+		}
+		if k != -1 {
+			j = first + uint32(k)
+		}
+	}
+	for ; j < last; j++ {
+		c, ci := &f.nodes[j], j
+		if best != -1 && (s.target != nil || c.max <= f.nodes[best].prio) {
+			continue // no better match down there
+		}
+		end, key, ok, bound := 0, "", false, len(path)+1
+		if c.m == -1 {
+			end, ok = len(c.lit), len(path) >= len(c.lit) && path[:len(c.lit)] == c.lit
+			if j < first+n.nlit {
+				j = first + n.nlit - 1 // skip the other leading literals
+			}
+		} else if f.middle(c) {
+			if bound = s.untried(c, len(path)); bound <= 0 {
+				continue // it failed there already
+			}
+			if end, key, ok = f.ms[c.m].match(path); end >= bound {
+				end, ok = f.retry(c, path, bound)
+			}
+		} else {
+			end, key, ok = f.match(c, path)
+		}
+		retrying := false
+		for ok && end < bound {
+			v := int32(-1)
+			if c.n != 0 {
+				v = f.lookup(ci, path[end:], params, s)
+			}
+			if c.value != -1 && end == len(path) && (v == -1 || c.prio > f.nodes[v].prio) &&
+				(s.target == nil || s.target == any(int32(ci))) {
+				v = int32(ci)
+			}
+			if v != -1 && (best == -1 || f.nodes[v].prio > f.nodes[best].prio) {
+				best = v
+				if params != nil && key != "" {
+					params[key] = strings.Clone(path[:end])
+					if cp, ok := f.ms[c.m].(capturer); ok {
+						cp.capture(path[:end], func(key, value string) {
+							params[key] = strings.Clone(value)
+						})
+					}
+				}
+				if s.target != nil || f.nodes[best].prio >= c.max {
+					break // no other split does better
+				}
+			}
+			ok = false
+			if c.m != -1 && s.backtrack {
+				if end, ok = f.retry(c, path, end); ok && !retrying {
+					retrying = true
+					s.retrying++
+				}
+			}
+		}
+		if retrying {
+			s.retrying--
+		}
+	}
+	return best
+}
+
+// match is node.match over the flat layout.
+func (f *table) match(c *fnode, path string) (int, string, bool) {
+	end, key, ok := f.ms[c.m].match(path)
+	if w, lazy := f.ms[c.m].(wildcard); lazy && w.lazy && c.n == 0 {
+		end = len(path)
+	}
+	return end, key, ok
+}
+
+// middle is node.middle over the flat layout.
+func (f *table) middle(c *fnode) bool {
+	_, ok := f.ms[c.m].(wildcard)
+	return ok && c.n != 0
+}
+
+// retry is node.retry over the flat layout.
+func (f *table) retry(c *fnode, path string, end int) (int, bool) {
+	w, ok := f.ms[c.m].(wildcard)
+	if !ok {
+		if r, ok := f.ms[c.m].(retrier); ok {
+			return r.retry(path, end)
+		}
+		return 0, false
+	}
+	next := -1
+	for i := c.first; i < c.first+c.n; i++ {
+		child := &f.nodes[i]
+		if child.m != -1 || child.lit == "" {
+			return w.retry(path, end)
+		}
+		next = w.closer(next, w.next(path, end, child.lit))
+	}
+	if next == -1 && w.lazy && c.value != -1 && end < len(path) {
+		next = len(path)
+	}
+	return next, next != -1
+}
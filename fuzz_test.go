@@ -0,0 +1,246 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// oracle is a naive reference for GetParam, independent of the tree: it
+// tries every split of the path by the parts of every pattern.
+type oracle struct {
+	r      *Router[string]
+	routes []oracleRoute
+}
+
+type oracleRoute struct {
+	pattern  string
+	priority int
+	parts    []Part
+	res      []*regexp.Regexp // of the Regex parts, parallel to parts
+}
+
+// add registers a pattern already accepted by the router.
+func (o *oracle) add(pattern string, priority int) {
+	parts, err := o.r.Parse(pattern)
+	if err != nil {
+		panic(err)
+	}
+	res := make([]*regexp.Regexp, len(parts))
+	for i, p := range parts {
+		if p.Kind == Regex {
+			res[i] = regexp.MustCompile("^(?:" + p.Expr + ")$")
+		}
+	}
+	o.routes = append(o.routes, oracleRoute{pattern, priority, parts, res})
+}
+
+// fits reports whether the i-th part of the route matches v, followed by the
+// rest of the path.
+func (o *oracle) fits(rt *oracleRoute, i int, v, rest string) bool {
+	sep := string(o.r.sep)
+	switch p := rt.parts[i]; p.Kind {
+	case Literal:
+		return v == p.Value
+	case Param:
+		// an empty segment too, as in fasthttp
+		return !strings.Contains(v, sep) && (v != "" || strings.HasPrefix(rest, sep))
+	case Regex:
+		return v != "" && (o.r.multi || !strings.Contains(v, sep)) && rt.res[i].MatchString(v)
+	case Wildcard:
+		return true
+	case Level:
+		return !strings.Contains(v, sep) && (i != 0 || !strings.HasPrefix(v, "$"))
+	case MultiLevel:
+		if i != 0 || strings.HasPrefix(rt.pattern, sep) {
+			return v == "" || strings.HasPrefix(v, sep) // the parent level too
+		}
+		return !strings.HasPrefix(v, "$")
+	}
+	return false
+}
+
+// splits calls f with the values of the parts from the i-th on, for every way
+// they match the path, until f returns false.
+func (o *oracle) splits(rt *oracleRoute, i int, path string, values []string, f func(values []string) bool) bool {
+	if i == len(rt.parts) {
+		return path != "" || f(values)
+	}
+	for end := 0; end <= len(path); end++ {
+		if o.fits(rt, i, path[:end], path[end:]) && !o.splits(rt, i+1, path[end:], append(values, path[:end]), f) {
+			return false
+		}
+	}
+	return true
+}
+
+// agree reports whether the params are those of the split, outer params
+// winning over inner ones of the same name, as in GetParam.
+func agree(rt *oracleRoute, values []string, params map[string]string) bool {
+	names := make(map[string]bool)
+	for i, p := range rt.parts {
+		if p.Kind == Literal || p.Value == "" || names[p.Value] {
+			continue
+		}
+		names[p.Value] = true
+		if v, ok := params[p.Value]; !ok || v != values[i] {
+			return false
+		}
+	}
+	return len(names) == len(params)
+}
+
+// checkOracle checks that GetParam returns a pattern matching the path, with
+// params splitting it by the parts of the pattern, and with backtracking, that
+// it finds a match whenever there's one, of the highest priority.
+func checkOracle(t testing.TB, r *Router[string], o *oracle, path string) {
+	t.Helper()
+	params := make(map[string]string)
+	got := r.GetParam(path, params)
+	best, found, split := 0, false, false
+	if o.r.routable(path) {
+		for i := range o.routes {
+			rt := &o.routes[i]
+			o.splits(rt, 0, path, nil, func(values []string) bool {
+				if !found || rt.priority > best {
+					best, found = rt.priority, true
+				}
+				if rt.pattern == got && agree(rt, values, params) {
+					split = true
+				}
+				return !split
+			})
+		}
+	}
+	patterns := make([]string, len(o.routes))
+	for i, rt := range o.routes {
+		patterns[i] = fmt.Sprintf("%s:%d", rt.pattern, rt.priority)
+	}
+	switch {
+	case got == "" && len(params) != 0:
+		t.Errorf("%q in %q: no match but params %v", path, patterns, params)
+	case got == "" && found && !o.r.fast:
+		t.Errorf("%q in %q: no match, want one of priority %d", path, patterns, best)
+	case got != "" && !split:
+		t.Errorf("%q in %q: got %q %v, which doesn't split the path", path, patterns, got, params)
+	case got != "" && !o.r.fast && o.priority(got) != best:
+		t.Errorf("%q in %q: got %q of priority %d, want priority %d", path, patterns, got, o.priority(got), best)
+	}
+}
+
+// priority returns the priority of the pattern.
+func (o *oracle) priority(pattern string) int {
+	for _, rt := range o.routes {
+		if rt.pattern == pattern {
+			return rt.priority
+		}
+	}
+	return 0
+}
+
+func TestRouterOracle(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   []Option
+		pieces []string
+		chars  string
+	}{
+		{"default", nil, []string{"/", "/", "a", "b", "ab", ".", "{x}", "{y+}", "{n:[0-9]+}", "{w:*}", "{v:*?}"}, "/ab1."},
+		{"fast", []Option{WithoutBacktracking()}, []string{"/", "/", "a", "b", "ab", ".", "{x}", "{y+}", "{n:[0-9]+}", "{w:*}", "{v:*?}"}, "/ab1."},
+		{"topic", []Option{WithTopicFilters()}, []string{"/", "/", "a", "b", "$", "+", "#"}, "/ab$"},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	random := func(pieces []string, n int) string {
+		var b strings.Builder
+		b.WriteByte('/')
+		for i := rnd.Intn(n); i > 0; i-- {
+			b.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		return b.String()
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chars := strings.Split(tc.chars, "")
+			for round := 0; round < 300; round++ {
+				r, frozen := NewRouter[string](tc.opts...), NewRouter[string](tc.opts...)
+				o := &oracle{r: r}
+				for i := 0; i < 8; i++ {
+					pattern, priority := random(tc.pieces, 5), 0
+					if rnd.Intn(3) == 0 {
+						priority = rnd.Intn(3) - 1
+					}
+					frozen.SetWithPriority(pattern, pattern, priority)
+					if r.SetWithPriority(pattern, pattern, priority) == nil {
+						o.add(pattern, priority)
+					}
+				}
+				if err := r.Check(); err != nil {
+					t.Fatal(err)
+				}
+				frozen.Freeze()
+				for i := 0; i < 50; i++ {
+					path := random(chars, 7)
+					checkOracle(t, r, o, path)
+					checkOracle(t, frozen, o, path)
+				}
+			}
+		})
+	}
+}
+
+func FuzzNext(f *testing.F) {
+	for _, seed := range []string{
+		"/", "/hello/{name}", "/{file}.{ext+}", "/{id:[0-9]{4}}", "/{c:(?<named>a)b}/{rest:*}",
+		"/{repo:*?}/-/blob", "/a{", "/{a}{b}", "/{:x}", "/sport/+/#", "+/a/#", "a+",
+	} {
+		f.Add(seed)
+	}
+	configs := []config{{sep: '/'}, {sep: '/', topic: true}, {sep: '/', noLeading: true, multi: true}}
+	f.Fuzz(func(t *testing.T, pattern string) {
+		for i := range configs {
+			c := &configs[i]
+			for path := pattern; path != ""; {
+				m, end, err := c.next(path, len(path) == len(pattern))
+				if err != nil {
+					break
+				}
+				if m == nil || end <= 0 || end > len(path) {
+					t.Fatalf("next(%q) = %v, %d", path, m, end)
+				}
+				path = path[end:]
+			}
+		}
+		r := NewRouter[string]()
+		if r.Set(pattern, pattern) == nil && !strings.Contains(pattern, "{") {
+			if got := r.Get(pattern); got != pattern {
+				t.Errorf("literal pattern %q matched %q", pattern, got)
+			}
+		}
+	})
+}
+
+func FuzzGetParam(f *testing.F) {
+	f.Add("/a/{x}\n/{w:*}\n/a/b", "/a/b")
+	f.Add("/{file}.{ext}\n/{name+}.gz", "/a.tar.gz")
+	f.Add("/{repo:*}/-/{ref}\n/{n:[0-9]+}/{rest:*?}", "/1/-/2")
+	f.Add("/{0:)|(}", "/0")
+	f.Add("/{0:0*?}", "/0")
+	f.Fuzz(func(t *testing.T, patterns, path string) {
+		if len(patterns) > 256 || len(path) > 64 {
+			t.Skip() // backtracking through several wildcards is exponential
+		}
+		r := NewRouter[string]()
+		o := &oracle{r: r}
+		for _, pattern := range strings.Split(patterns, "\n") {
+			if r.Set(pattern, pattern) == nil {
+				o.add(pattern, 0)
+			}
+		}
+		if err := r.Check(); err != nil {
+			t.Fatal(err)
+		}
+		checkOracle(t, r, o, path)
+	})
+}
@@ -0,0 +1,44 @@
+// Package tree exposes the routing trees of routers to the commands of the
+// module, which can't reach their unexported nodes.
+package tree
+
+// Kind is the kind of matcher of a Node, as router.PartKind.
+type Kind int
+
+const (
+	Literal Kind = iota
+	Param
+	Regex
+	Wildcard
+	Level
+	MultiLevel
+)
+
+// Node is a node of a routing tree, its children in the order they're tried.
+type Node struct {
+	Kind     Kind
+	Value    string // the literal, or the key of the param
+	After    string // the suffix of a Param
+	Greedy   bool   // Param
+	Lazy     bool   // Wildcard
+	Re, Full string // the expression of a Regex anchored at the start, and at both ends
+	Multi    bool   // Regex, matching across separators
+	First    bool   // Level and MultiLevel, at the beginning of the filter
+	Parent   bool   // MultiLevel, matching the parent level too
+	Sep      byte
+	Pattern  string
+	Assigned bool
+	Children []*Node
+}
+
+// Tree is the routing tree of a router along with its options.
+type Tree struct {
+	Root      Node
+	Sep       byte
+	NoLeading bool
+	Topic     bool
+	Fast      bool
+}
+
+// Of returns the tree of a *router.Router, set by package router.
+var Of func(r any) (*Tree, bool)
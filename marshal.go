@@ -0,0 +1,72 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonRoute is a Route as encoded by MarshalRoutes.
+type jsonRoute struct {
+	Pattern  string          `json:"pattern"`
+	Name     string          `json:"name,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"`  // if encoded as JSON
+	Binary   []byte          `json:"binary,omitempty"` // base64, otherwise
+}
+
+// MarshalRoutes encodes the registered routes as a JSON array, in the order
+// they were registered. The values are encoded by c, or as by MarshalBinary
+// if c has no Encode, and embedded as is if that's JSON, or in base64 if not,
+// so any encoding goes. Only JSON is supported, not YAML. It's routine-safe.
+func (r *Router[T]) MarshalRoutes(c Codec[T]) ([]byte, error) {
+	encode := r.encode
+	if c.Encode != nil {
+		encode = c.Encode
+	}
+	routes := r.Routes()
+	out := make([]jsonRoute, len(routes))
+	for i, route := range routes {
+		value, err := encode(route.Value)
+		if err != nil {
+			return nil, fmt.Errorf("encoding the value of '%s': %w", route.Pattern, err)
+		}
+		out[i] = jsonRoute{Pattern: route.Pattern, Name: route.Name, Priority: route.Priority}
+		if json.Valid(value) {
+			out[i].Value = value
+		} else {
+			out[i].Binary = value
+		}
+	}
+	return json.Marshal(out)
+}
+
+// LoadRoutes registers the routes encoded by MarshalRoutes, decoding the
+// values by c, or as by UnmarshalBinary if c has no Decode. Values embedded as
+// JSON are decoded from compacted JSON. Given a Router with the same options,
+// the routes are registered as in the Router they were encoded from. Either
+// all the routes are registered or none, if a value fails to decode or a
+// route to register, the errors of the latter being returned as Errors.
+// It's not routine-safe.
+func (r *Router[T]) LoadRoutes(data []byte, c Codec[T]) error {
+	decode := r.decode
+	if c.Decode != nil {
+		decode = c.Decode
+	}
+	var encoded []jsonRoute
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	tx := r.Begin()
+	for _, route := range encoded {
+		data := route.Binary
+		if route.Value != nil {
+			data = route.Value
+		}
+		value, err := decode(data)
+		if err != nil {
+			return fmt.Errorf("decoding the value of '%s': %w", route.Pattern, err)
+		}
+		tx.SetRoute(Route[T]{Pattern: route.Pattern, Name: route.Name, Priority: route.Priority, Value: value})
+	}
+	return tx.Commit()
+}
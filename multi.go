@@ -0,0 +1,82 @@
+package router
+
+// MultiRouter is a Router holding several values per pattern, in the order
+// they were added.
+type MultiRouter[T any] struct {
+	r Router[[]T]
+}
+
+// Add appends a value to the given URL pattern. It's not routine-safe.
+func (m *MultiRouter[T]) Add(path string, value T) error {
+	if err := m.r.check(path); err != nil {
+		return err
+	}
+	n, err := m.r.tree.insert(path, 0, false, &m.r.config)
+	if err != nil {
+		return err
+	}
+	n.handler = append(n.handler, value)
+	n.pattern = path
+	n.assigned = true
+	m.r.tree.sort()
+	m.r.mutated()
+	return nil
+}
+
+// RemoveValue removes the values of the given URL pattern for which eq
+// returns true, and returns how many were removed. It's not routine-safe.
+func (m *MultiRouter[T]) RemoveValue(path string, eq func(T) bool) int {
+	n := m.r.tree.find(path, &m.r.config)
+	if n == nil || !n.assigned {
+		return 0
+	}
+	values := make([]T, 0, len(n.handler))
+	for _, v := range n.handler {
+		if !eq(v) {
+			values = append(values, v)
+		}
+	}
+	removed := len(n.handler) - len(values)
+	if len(values) == 0 {
+		n.handler, n.pattern, n.assigned = nil, "", false
+	} else {
+		n.handler = values
+	}
+	m.r.mutated()
+	return removed
+}
+
+// GetParam matches the given path and returns the values of the
+// corresponding pattern, assigning the given params map with the matched
+// parameters. The returned slice must not be modified. It's routine-safe.
+func (m *MultiRouter[T]) GetParam(path string, params map[string]string) []T {
+	return m.r.GetParam(path, params)
+}
+
+// Get matches the given path and returns the values of the corresponding
+// pattern. The returned slice must not be modified. It's routine-safe.
+func (m *MultiRouter[T]) Get(path string) []T {
+	return m.r.GetParam(path, nil)
+}
+
+// GetAllMatches calls f with the values of every pattern matching the path,
+// until f returns false. Patterns come in MostSpecific order, values of the
+// same pattern in the order they were added. It's routine-safe.
+func (m *MultiRouter[T]) GetAllMatches(path string, f func(T) (more bool)) {
+	if f == nil {
+		return
+	}
+	m.r.GetAllMatches(path, func(values []T) bool {
+		for _, v := range values {
+			if !f(v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// NewMultiRouter creates a MultiRouter, see NewRouter for the options.
+func NewMultiRouter[T any](opts ...Option) *MultiRouter[T] {
+	return &MultiRouter[T]{r: *NewRouter[[]T](opts...)}
+}
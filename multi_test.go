@@ -0,0 +1,56 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMultiRouter(t *testing.T) {
+	r := NewMultiRouter[string]()
+	r.Add("/events/{name}", "log")
+	r.Add("/events/{name}", "audit")
+	r.Add("/events/{name}", "metrics")
+	r.Add("/events/login", "security")
+	r.Add("/{path:*}", "fallback")
+	if err := r.Add("/events/{other}", "conflict"); err == nil {
+		t.Errorf("expected conflict error")
+	}
+
+	params := make(map[string]string)
+	if got, want := r.GetParam("/events/logout", params), []string{"log", "audit", "metrics"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if params["name"] != "logout" {
+		t.Errorf("expected param name to be logout, got %v", params)
+	}
+
+	var got []string
+	r.GetAllMatches("/events/login", func(v string) bool {
+		got = append(got, v)
+		return true
+	})
+	if want := []string{"security", "log", "audit", "metrics", "fallback"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	before := r.Get("/events/logout")
+	if removed := r.RemoveValue("/events/{name}", func(v string) bool { return v == "audit" }); removed != 1 {
+		t.Errorf("expected to remove 1 value, removed %d", removed)
+	}
+	if got, want := r.Get("/events/logout"), []string{"log", "metrics"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if want := []string{"log", "audit", "metrics"}; !reflect.DeepEqual(before, want) {
+		t.Errorf("expected previously returned values to be kept, got %v", before)
+	}
+	if removed := r.RemoveValue("/events/{nam}", func(string) bool { return true }); removed != 0 {
+		t.Errorf("expected to remove nothing for an unknown pattern, removed %d", removed)
+	}
+	r.RemoveValue("/events/{name}", func(string) bool { return true })
+	if got, want := r.Get("/events/logout"), []string{"fallback"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if err := r.Add("/events/{name}", "log"); err != nil {
+		t.Errorf("expected no error adding to an emptied pattern, got %v", err)
+	}
+}
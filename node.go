@@ -1,132 +1,525 @@
 package router
 
 import (
+	"math"
 	"sort"
 	"strings"
 )
 
-func (n *node[T]) add(path, fullPath string, handler T) (*node[T], error) {
-	for path != "" {
-		next, end, err := next(path)
+func (n *node[T]) add(pattern string, handler T, priority int, c *config) (*node[T], error) {
+	n, err := n.insert(pattern, priority, true, c)
+	if err != nil {
+		return nil, err
+	}
+	n.handler = handler
+	n.pattern = pattern
+	n.priority = priority
+	n.assigned = true
+	return n, nil
+}
+
+// insert returns the node for the pattern, creating it if necessary. A nonzero
+// priority is checked against the routes of the branches the pattern leaves,
+// and if unique, the node must not be assigned already. On error the tree is
+// left untouched: the pattern is parsed and checked before any change.
+func (n *node[T]) insert(pattern string, priority int, unique bool, c *config) (*node[T], error) {
+	ms, err := c.parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := n.walk(ms, pattern, priority, false)
+	if err != nil {
+		return nil, err
+	}
+	if unique && existing != nil && existing.assigned {
+		return nil, ErrConflict.With(pattern)
+	}
+	return n.walk(ms, pattern, priority, true)
+}
+
+// parse splits the pattern into matchers.
+func (c *config) parse(pattern string) ([]matcher, error) {
+	var ms []matcher
+	for path := pattern; path != ""; {
+		m, end, err := c.next(path, len(path) == len(pattern))
 		if err != nil {
 			return nil, err
 		}
-		inserted := false
-		for _, child := range n.children {
+		path = path[end:]
+		if _, ok := m.(wildcard); ok && path != "" && c.fast {
+			// only backtracking finds where the rest of the pattern starts
+			return nil, ErrWildcardNotAtEnd.With(pattern)
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// walk follows the matchers down the tree, checking for conflicts where the
+// pattern branches off. Unless mutate, it stops where the pattern leaves the
+// tree, returning a nil node, otherwise it splits literals and creates the
+// missing nodes, returning the node of the pattern.
+func (n *node[T]) walk(ms []matcher, pattern string, priority int, mutate bool) (*node[T], error) {
+	ms = append([]matcher(nil), ms...) // literals are consumed in place
+	for len(ms) != 0 {
+		next := ms[0]
+		into, split := -1, 0
+		for i, child := range n.children {
 			if child.m.equal(next) {
-				n = child
-				inserted = true
-				path = path[end:]
+				into = i
 				break
 			}
 		}
-
-		switch next := next.(type) {
-		case literal:
-			maxi, maxl := -1, 0
+		l, lit := next.(literal)
+		if lit && into == -1 {
 			for i, child := range n.children {
-				l, ok := child.m.(literal)
-				if !ok || string(l)[0] != path[0] {
-					continue
+				// literal siblings never share the first byte
+				if cl, ok := child.m.(literal); ok && cl != "" && cl[0] == l[0] {
+					into, split = i, lcp(string(cl), string(l))
+					break
 				}
-				if common := lcp(string(l), string(next)); common > maxl {
-					maxi, maxl = i, common
+			}
+		}
+		if into == -1 {
+			switch next.(type) {
+			case wildcard, param:
+				for _, child := range n.children {
+					if typeID(child.m) == typeID(next) {
+						return nil, ErrExprConflict.With(pattern, child.m.string())
+					}
 				}
 			}
-			if maxi != -1 {
-				// split node
-				child := n.children[maxi]
-				n = child.cut(maxl)
-				path = path[maxl:]
-				inserted = true
-				break
+		}
+		if priority != 0 {
+			for i, child := range n.children {
+				// siblings of different kinds are ordered by the default
+				// precedence, literal ones never overlap
+				if i != into && typeID(child.m) != 0 && typeID(child.m) == typeID(next) && child.has(priority) {
+					return nil, ErrPriorityConflict.With(pattern, priority, child.m.string())
+				}
 			}
-		case wildcard, param:
-			for _, child := range n.children {
-				if typeID(child.m) == typeID(next) {
-					return nil, ErrExprConflict.With(fullPath, child.m.string())
+		}
+		if split != 0 {
+			child := n.children[into]
+			if cl := child.m.(literal); split < len(cl) {
+				if !mutate {
+					return nil, nil
 				}
+				child.cut(split)
+			}
+			n = child
+			if split < len(l) {
+				ms[0] = l[split:]
+			} else {
+				ms = ms[1:]
 			}
+			continue
 		}
-		if inserted {
+		if into != -1 {
+			n = n.children[into]
+			ms = ms[1:]
 			continue
 		}
-		path = path[end:]
-		if _, ok := next.(wildcard); ok && path != "" {
-			return nil, ErrWildcardNotAtEnd.With(fullPath)
+		if !mutate {
+			return nil, nil
 		}
 		newch := &node[T]{m: next}
-		if next, ok := next.(literal); ok {
-			newch.b = next[0]
+		if lit {
+			newch.b = l[0]
 		}
 		n.children = append(n.children, newch)
 		n = newch
+		ms = ms[1:]
 	}
-	if n.assigned {
-		return nil, ErrConflict.With(fullPath)
-	}
-	n.handler = handler
-	n.assigned = true
 	return n, nil
 }
 
-func (n *node[T]) get(path string, params map[string]string) *node[T] {
-	for i := 0; i < len(n.children); i++ {
+// has reports whether a route with the given priority is in the subtree.
+func (n *node[T]) has(priority int) bool {
+	if n.assigned && n.priority == priority {
+		return true
+	}
+	for _, child := range n.children {
+		if child.has(priority) {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the node registered for the path, or nil if there is none.
+func (n *node[T]) find(path string, c *config) *node[T] {
+	if trail := n.trail(path, c); trail != nil {
+		return trail[len(trail)-1]
+	}
+	return nil
+}
+
+// trail returns the nodes from n down to the node registered for the path,
+// or nil if there is none.
+func (n *node[T]) trail(path string, c *config) []*node[T] {
+	fullPath, trail := path, []*node[T]{n}
+outer:
+	for path != "" {
+		next, end, err := c.next(path, len(path) == len(fullPath))
+		if err != nil {
+			return nil
+		}
+		for _, child := range n.children {
+			if l, ok := child.m.(literal); ok && len(l) != 0 {
+				// literals may be split across several nodes
+				if next, ok := next.(literal); ok && strings.HasPrefix(string(next), string(l)) {
+					n, path = child, path[len(l):]
+					trail = append(trail, n)
+					continue outer
+				}
+			} else if child.m.equal(next) {
+				n, path = child, path[end:]
+				trail = append(trail, n)
+				continue outer
+			}
+		}
+		return nil
+	}
+	return trail
+}
+
+// remove unassigns the node registered for the pattern and returns a copy of
+// it, or nil if there is none. The branches left without routes are pruned
+// and the literals split for them joined again, as if the pattern had never
+// been registered, leaving the tree to sort.
+func (n *node[T]) remove(pattern string, c *config) *node[T] {
+	trail := n.trail(pattern, c)
+	if trail == nil || !trail[len(trail)-1].assigned {
+		return nil
+	}
+	leaf := trail[len(trail)-1]
+	removed := &node[T]{handler: leaf.handler, pattern: leaf.pattern, name: leaf.name, seq: leaf.seq, priority: leaf.priority, assigned: true}
+	var zero T
+	leaf.handler, leaf.pattern, leaf.name, leaf.seq, leaf.priority, leaf.assigned = zero, "", "", 0, 0, false
+	for i := len(trail) - 1; i > 0; i-- {
+		x, parent := trail[i], trail[i-1]
+		if !x.assigned && len(x.children) == 0 {
+			for j, child := range parent.children {
+				if child == x {
+					parent.children = append(parent.children[:j], parent.children[j+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		l, ok := x.m.(literal)
+		if !ok || x.assigned || len(x.children) != 1 {
+			break
+		}
+		child := x.children[0]
+		if cl, ok := child.m.(literal); ok {
+			*x = node[T]{
+				m: l + cl, b: x.b, children: child.children,
+				handler: child.handler, pattern: child.pattern, assigned: child.assigned,
+				name: child.name, seq: child.seq, priority: child.priority,
+			}
+		}
+		break
+	}
+	return removed
+}
+
+// search holds the state of a lookup backtracking through the tree.
+type search struct {
+	backtrack bool
+	retrying  int         // retriers trying another split of the path
+	tried     map[any]int // middle wildcards, *node[T] or *fnode, to the longest rest of the path tried
+	target    any         // the only node to match, to find its params
+}
+
+// untried returns the bound below which the splits of the rest of the path by
+// a middle wildcard weren't tried yet, and records them as tried. The subtree
+// matches the same whichever split led there, so each of its spots is tried
+// once rather than exponentially many times with several wildcards.
+func (s *search) untried(n any, rest int) int {
+	if s.retrying == 0 {
+		return rest + 1 // the node is reached once
+	}
+	tried, ok := s.tried[n]
+	if !ok {
+		tried = -1
+	}
+	if rest > tried {
+		if s.tried == nil {
+			s.tried = make(map[any]int)
+		}
+		s.tried[n] = rest
+	}
+	return rest - tried
+}
+
+// get returns the node of the highest priority whose pattern matches the
+// path, the first one trying siblings in sorted order among equal priorities.
+// With backtrack, matchers able to match several prefixes of the path retry
+// with longer ones before giving up on a child.
+func (n *node[T]) get(path string, params map[string]string, backtrack bool) *node[T] {
+	if n.maxprio == n.minprio {
+		return n.lookup(path, params, &search{backtrack: backtrack})
+	}
+	// the params of the matches outdone by later ones would linger
+	best := n.lookup(path, nil, &search{backtrack: backtrack})
+	if best != nil && params != nil {
+		n.lookup(path, params, &search{backtrack: backtrack, target: best})
+	}
+	return best
+}
+
+func (n *node[T]) lookup(path string, params map[string]string, s *search) (best *node[T]) {
+	for i := n.first(path); i < len(n.children); i++ {
 		child, end, key, ok := n.children[i], 0, "", false
+		if best != nil && (s.target != nil || child.maxprio <= best.priority) {
+			continue // no better match down there
+		}
+		bound := len(path) + 1
 		if child.b != 0 {
 			if path == "" || path[0] != child.b {
 				continue
 			}
+			if i < n.lastlit {
+				i = n.lastlit // no other literal sibling starts with the byte
+			}
 			if end, key, ok = child.m.(literal).match(path); !ok {
 				continue
 			}
-			i = n.lastlit
-		} else if end, key, ok = child.m.match(path); !ok {
+		} else if child.middle() {
+			if bound = s.untried(child, len(path)); bound <= 0 {
+				continue // it failed there already
+			}
+			if end, key, ok = child.m.match(path); end >= bound {
+				end, ok = child.retry(path, bound)
+			}
+		} else if end, key, ok = child.match(path); !ok {
 			continue
 		}
-		var next *node[T]
-		if len(child.children) != 0 {
-			next = child.get(path[end:], params)
-		}
-		if next == nil {
-			if !child.assigned || end != len(path) {
-				continue
+		retrying := false
+		for ok && end < bound {
+			var next *node[T]
+			if len(child.children) != 0 {
+				next = child.lookup(path[end:], params, s)
+			}
+			if child.assigned && end == len(path) && (next == nil || child.priority > next.priority) &&
+				(s.target == nil || s.target == any(child)) {
+				next = child
+			}
+			if next != nil && (best == nil || next.priority > best.priority) {
+				best = next
+				if params != nil && key != "" {
+					params[key] = strings.Clone(path[:end])
+					if c, ok := child.m.(capturer); ok {
+						c.capture(path[:end], func(key, value string) {
+							params[key] = strings.Clone(value)
+						})
+					}
+				}
+				if s.target != nil || best.priority >= child.maxprio {
+					break // no other split does better
+				}
+			}
+			ok = false
+			if s.backtrack {
+				if end, ok = child.retry(path, end); ok && !retrying {
+					retrying = true
+					s.retrying++
+				}
 			}
-			next = child
 		}
-		if params != nil && key != "" {
-			params[key] = strings.Clone(path[:end])
+		if retrying {
+			s.retrying--
 		}
-		return next
 	}
-	return nil
+	return best
+}
+
+// match matches the expression of the node against the path. A lazy wildcard
+// ending the pattern takes the rest of the path at once, since nothing follows
+// to stop it, even without backtracking.
+func (n *node[T]) match(path string) (int, string, bool) {
+	end, key, ok := n.m.match(path)
+	if w, lazy := n.m.(wildcard); lazy && w.lazy && len(n.children) == 0 {
+		end = len(path)
+	}
+	return end, key, ok
+}
+
+// middle reports whether the node is a wildcard followed by more of a pattern.
+func (n *node[T]) middle() bool {
+	_, ok := n.m.(wildcard)
+	return ok && len(n.children) != 0
+}
+
+// retry returns the next prefix of the path to try after path[:end], see
+// retrier. A wildcard only stops where one of its literal children follows,
+// or at the end of the path for its own route, rather than at every byte.
+func (n *node[T]) retry(path string, end int) (int, bool) {
+	w, ok := n.m.(wildcard)
+	if !ok {
+		if r, ok := n.m.(retrier); ok {
+			return r.retry(path, end)
+		}
+		return 0, false
+	}
+	next := -1
+	for _, child := range n.children {
+		l, ok := child.m.(literal)
+		if !ok || l == "" {
+			return w.retry(path, end)
+		}
+		next = w.closer(next, w.next(path, end, string(l)))
+	}
+	if next == -1 && w.lazy && n.assigned && end < len(path) {
+		next = len(path)
+	}
+	return next, next != -1
 }
 
-func (n *node[T]) getcb(path string, f func(n T) (more bool)) (has bool) {
-	for i := 0; i < len(n.children); i++ {
-		child, end, ok := n.children[i], 0, false
+// walkAll holds the state of a getall walk, apart from the callback which
+// would escape along with the nodes seen.
+type walkAll[T any] struct {
+	search
+	seen []*node[T] // nodes reported while retrying
+}
+
+// report calls f with the node, once even if several splits reach it.
+func (w *walkAll[T]) report(n *node[T], params []string, f func(n *node[T], params []string) (more bool)) bool {
+	if w.retrying != 0 {
+		for _, s := range w.seen {
+			if s == n {
+				return true
+			}
+		}
+		w.seen = append(w.seen, n)
+	}
+	return f(n, params)
+}
+
+// getall calls f with every node whose pattern matches the path, descendants
+// before their parents and siblings in sorted order, which is the order get
+// prefers them. If params is not nil, the matched params are appended to it as
+// key-value pairs, f must copy them to retain. It returns false if f asked to stop.
+func (n *node[T]) getall(path string, params []string, w *walkAll[T], f func(n *node[T], params []string) (more bool)) bool {
+	for i := n.first(path); i < len(n.children); i++ {
+		child, end, key, ok := n.children[i], 0, "", false
+		bound := len(path) + 1
 		if child.b != 0 {
 			if path == "" || path[0] != child.b {
 				continue
 			}
-			if end, _, ok = child.m.(literal).match(path); !ok {
+			if i < n.lastlit {
+				i = n.lastlit // no other literal sibling starts with the byte
+			}
+			if end, key, ok = child.m.(literal).match(path); !ok {
 				continue
 			}
-			i = n.lastlit
-		} else if end, _, ok = child.m.match(path); !ok {
+		} else if child.middle() {
+			if bound = w.untried(child, len(path)); bound <= 0 {
+				continue // its matches were reported already
+			}
+			if end, key, ok = child.m.match(path); end >= bound {
+				end, ok = child.retry(path, bound)
+			}
+			if !ok || end >= bound {
+				continue
+			}
+		} else if end, key, ok = child.match(path); !ok {
 			continue
 		}
-		if len(child.children) == 0 || !child.getcb(path[end:], f) {
-			if !child.assigned || end != len(path) {
-				continue
+		next, more := 0, false
+		if w.backtrack {
+			next, more = child.retry(path, end)
+			more = more && next < bound
+		}
+		// the splits of a leaf can't reach it twice, it must match entirely
+		retrying := more && len(child.children) != 0
+		if retrying {
+			w.retrying++
+		}
+		for {
+			if !child.getall1(path, end, key, params, w, f) {
+				return false
 			}
-			if !f(child.handler) {
-				return true
+			if !more {
+				break
 			}
+			end = next
+			next, more = child.retry(path, end)
+			more = more && next < bound
+		}
+		if retrying {
+			w.retrying--
 		}
-		has = true // no return here, iterate entire tree
 	}
-	return
+	return true
+}
+
+// getall1 is getall for a single child, which matched path[:end].
+func (n *node[T]) getall1(path string, end int, key string, params []string, w *walkAll[T], f func(n *node[T], params []string) (more bool)) bool {
+	if params != nil && key != "" {
+		params = append(params, key, path[:end])
+		if c, ok := n.m.(capturer); ok {
+			params = appendCaptures(params, c, path[:end])
+		}
+	}
+	if len(n.children) != 0 && !n.getall(path[end:], params, w, f) {
+		return false
+	}
+	return !n.assigned || end != len(path) || w.report(n, params, f)
+}
+
+// appendCaptures appends the extra params of the matched string to params,
+// apart from getall1 whose params would escape for every call otherwise.
+func appendCaptures(params []string, c capturer, s string) []string {
+	c.capture(s, func(key, value string) {
+		params = append(params, key, value)
+	})
+	return params
+}
+
+// route describes the route of an assigned node.
+func (n *node[T]) route() Route[T] {
+	return Route[T]{Pattern: n.pattern, Name: n.name, Priority: n.priority, Value: n.handler}
+}
+
+// each calls f with every assigned node of the subtree.
+func (n *node[T]) each(f func(n *node[T])) {
+	if n.assigned {
+		f(n)
+	}
+	for _, child := range n.children {
+		child.each(f)
+	}
+}
+
+// paths calls f with every assigned node reachable through literals only, along
+// with its key, skipping subtrees that diverge from the prefix.
+// It returns false if f asked to stop.
+func (n *node[T]) paths(key []byte, prefix string, f func(key string, n *node[T]) (more bool)) bool {
+	for _, child := range n.children {
+		l, ok := child.m.(literal)
+		if !ok {
+			continue
+		}
+		key := append(key, l...)
+		common := len(key)
+		if len(prefix) < common {
+			common = len(prefix)
+		}
+		if string(key[:common]) != prefix[:common] {
+			continue
+		}
+		if child.assigned && !f(string(key), child) {
+			return false
+		}
+		if !child.paths(key, prefix, f) {
+			return false
+		}
+	}
+	return true
 }
 
 func (n *node[T]) cut(i int) *node[T] {
@@ -139,21 +532,91 @@ func (n *node[T]) cut(i int) *node[T] {
 	}
 	n.children = []*node[T]{{
 		m: l[i:], b: l[i], children: n.children,
-		handler: n.handler, assigned: n.assigned,
+		handler: n.handler, pattern: n.pattern, assigned: n.assigned,
+		name: n.name, seq: n.seq, priority: n.priority, maxprio: n.maxprio, minprio: n.minprio,
 	}}
 	var zero T
 	n.handler = zero
+	n.pattern = ""
+	n.name = ""
+	n.seq = 0
+	n.priority = 0
 	n.assigned = false
 	n.m = l[:i]
 	return n
 }
 
 func (n *node[T]) sort() {
+	for _, child := range n.children {
+		child.sort()
+	}
+	n.maxprio, n.minprio = n.prios()
 	sort.Sort(n)
+	n.reindex()
+}
+
+// prios returns the highest and lowest priorities of the routes in the
+// subtree, from those of the children, or 0 if there are none.
+func (n *node[T]) prios() (max, min int) {
+	max, min = math.MinInt, math.MaxInt
+	if n.assigned {
+		max, min = n.priority, n.priority
+	}
+	for _, child := range n.children {
+		if child.maxprio > max {
+			max = child.maxprio
+		}
+		if child.minprio < min {
+			min = child.minprio
+		}
+	}
+	if max == math.MinInt {
+		return 0, 0
+	}
+	return max, min
+}
+
+// reindex computes lastlit and index from the sorted children.
+func (n *node[T]) reindex() {
+	n.lastlit = 0
 	for i, child := range n.children {
-		if _, ok := child.m.(literal); ok {
-			n.lastlit = i
+		if _, ok := child.m.(literal); !ok {
+			break
+		}
+		n.lastlit = i
+	}
+	index := n.index
+	n.index = nil
+	if n.lastlit+1 < indexMin {
+		return
+	}
+	if index == nil {
+		index = new([256]uint16)
+	} else {
+		*index = [256]uint16{}
+	}
+	for i, child := range n.children[:n.lastlit+1] {
+		if child.b == 0 {
+			return // empty literal or starting with the NUL byte
+		}
+		index[child.b] = uint16(i + 1)
+	}
+	n.index = index
+}
+
+// indexMin is the number of leading literal children from which they are
+// indexed by their first byte instead of scanned.
+const indexMin = 8
+
+// first returns the index of the first child which may match the path.
+func (n *node[T]) first(path string) int {
+	if n.index == nil {
+		return 0
+	}
+	if path != "" {
+		if i := n.index[path[0]]; i != 0 {
+			return int(i - 1)
 		}
-		child.sort()
 	}
+	return n.lastlit + 1
 }
@@ -0,0 +1,161 @@
+// Package openapi describes the routes of routers as the paths of an
+// OpenAPI 3 document.
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	router "github.com/frankli0324/go-router"
+)
+
+// Paths is the paths object of an OpenAPI document, mapping path templates
+// to their operations. It marshals to JSON as is.
+type Paths map[string]PathItem
+
+// PathItem maps lowercase HTTP methods to operations.
+type PathItem map[string]*Operation
+
+// Operation describes a route.
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Response describes a response of an Operation.
+type Response struct {
+	Description string `json:"description"`
+}
+
+// Parameter describes a path param.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+// Schema describes the values of a Parameter.
+type Schema struct {
+	Type    string `json:"type"`
+	Format  string `json:"format,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// typed maps regexes to the schemas of the values they constrain.
+var typed = map[string]Schema{
+	`[0-9]+`:     {Type: "integer", Format: "int64"},
+	`\d+`:        {Type: "integer", Format: "int64"},
+	`true|false`: {Type: "boolean"},
+	`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`: {Type: "string", Format: "uuid"},
+}
+
+// Add adds the routes of a router handling the given HTTP method to the
+// paths, one operation per pattern named after the route. Params become
+// path params, regexes their pattern, or their type and format for the well
+// known ones like [0-9]+, and wildcards catch-all params. OpenAPI has no
+// params inside params, so the named groups of regexes are only listed in the
+// description of their param. It fails for patterns that can't be told apart
+// in OpenAPI, like "/{id}" and "/{id:[0-9]+}" under the same method, and for
+// MQTT topic filters.
+func Add[T any](paths Paths, method string, r *router.Router[T]) error {
+	method = strings.ToLower(method)
+	for _, route := range r.Routes() {
+		parts, err := r.Parse(route.Pattern)
+		if err != nil {
+			return err
+		}
+		template, op, err := operation(parts)
+		if err != nil {
+			return fmt.Errorf("pattern '%s': %w", route.Pattern, err)
+		}
+		op.OperationID = route.Name
+		item := paths[template]
+		if item == nil {
+			item = make(PathItem)
+			paths[template] = item
+		}
+		if item[method] != nil {
+			return fmt.Errorf("pattern '%s': %s %s is already described by another pattern", route.Pattern, strings.ToUpper(method), template)
+		}
+		item[method] = op
+	}
+	return nil
+}
+
+// Methods returns the paths of routers keyed by HTTP method, see Add.
+func Methods[T any](routers map[string]*router.Router[T]) (Paths, error) {
+	methods := make([]string, 0, len(routers))
+	for method := range routers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods) // report the same error every time
+	paths := make(Paths)
+	for _, method := range methods {
+		if err := Add(paths, method, routers[method]); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// named returns the names of the named groups of a regex.
+func named(expr string) []string {
+	// (?<name>) is only understood by regexp since go1.22
+	re, err := regexp.Compile(strings.ReplaceAll(expr, "(?<", "(?P<"))
+	if err != nil {
+		return nil // the router compiled it already
+	}
+	var names []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// operation returns the path template and the operation of a pattern.
+func operation(parts []router.Part) (string, *Operation, error) {
+	var b strings.Builder
+	op := &Operation{Responses: map[string]Response{"default": {Description: "default response"}}}
+	for i, part := range parts {
+		if part.Kind == router.Literal {
+			b.WriteString(part.Value)
+			continue
+		}
+		p := Parameter{Name: part.Value, In: "path", Required: true, Schema: Schema{Type: "string"}}
+		switch part.Kind {
+		case router.Regex:
+			if s, ok := typed[part.Expr]; ok {
+				p.Schema = s
+			} else {
+				// ECMA regexes spell named groups (?<name>) and aren't anchored
+				p.Schema.Pattern = "^(?:" + strings.ReplaceAll(part.Expr, "(?P<", "(?<") + ")$"
+			}
+			if groups := named(part.Expr); len(groups) != 0 {
+				p.Description = "named groups, reported as params too: " + strings.Join(groups, ", ")
+			}
+		case router.Wildcard:
+			if i == len(parts)-1 {
+				p.Description = "catch-all, matches the rest of the path including '/', possibly empty"
+			} else {
+				p.Description = "matches any part of the path including '/', possibly empty, up to what follows"
+			}
+		case router.Level, router.MultiLevel:
+			return "", nil, fmt.Errorf("MQTT wildcards have no OpenAPI equivalent")
+		}
+		for _, q := range op.Parameters {
+			if q.Name == p.Name {
+				return "", nil, fmt.Errorf("param '%s' appears twice", p.Name)
+			}
+		}
+		b.WriteString("{" + p.Name + "}")
+		op.Parameters = append(op.Parameters, p)
+	}
+	return b.String(), op, nil
+}
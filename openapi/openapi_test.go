@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	router "github.com/frankli0324/go-router"
+)
+
+func TestMethods(t *testing.T) {
+	get, post := router.NewRouter[int](), router.NewRouter[int]()
+	get.SetRoute(router.Route[int]{Pattern: "/users/{id:[0-9]+}", Name: "getUser"})
+	get.Set("/files/{name}.tar.gz", 0)
+	get.Set("/static/{path:*}", 0)
+	get.Set("/repos/{repo:*}/-/blob", 0)
+	get.Set("/v{n:v(?P<major>[1-9])}", 0)
+	post.SetRoute(router.Route[int]{Pattern: "/users/{id:[0-9]+}", Name: "updateUser"})
+
+	paths, err := Methods(map[string]*router.Router[int]{"GET": get, "POST": post})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	enc := json.NewEncoder(&got)
+	enc.SetEscapeHTML(false)
+	enc.Encode(paths)
+	want := `{` +
+		`"/files/{name}.tar.gz":{"get":{"parameters":[{"name":"name","in":"path","required":true,"schema":{"type":"string"}}],"responses":{"default":{"description":"default response"}}}},` +
+		`"/repos/{repo}/-/blob":{"get":{"parameters":[{"name":"repo","in":"path","required":true,"description":"matches any part of the path including '/', possibly empty, up to what follows","schema":{"type":"string"}}],"responses":{"default":{"description":"default response"}}}},` +
+		`"/static/{path}":{"get":{"parameters":[{"name":"path","in":"path","required":true,"description":"catch-all, matches the rest of the path including '/', possibly empty","schema":{"type":"string"}}],"responses":{"default":{"description":"default response"}}}},` +
+		`"/users/{id}":{` +
+		`"get":{"operationId":"getUser","parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64"}}],"responses":{"default":{"description":"default response"}}},` +
+		`"post":{"operationId":"updateUser","parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64"}}],"responses":{"default":{"description":"default response"}}}},` +
+		`"/v{n}":{"get":{"parameters":[{"name":"n","in":"path","required":true,"description":"named groups, reported as params too: major","schema":{"type":"string","pattern":"^(?:v(?<major>[1-9]))$"}}],"responses":{"default":{"description":"default response"}}}}` +
+		`}` + "\n"
+	if got.String() != want {
+		t.Errorf("got  %s\nwant %s", got.String(), want)
+	}
+
+	get.Set("/users/{id}", 0)
+	if _, err := Methods(map[string]*router.Router[int]{"GET": get}); err == nil {
+		t.Error("expected /users/{id} and /users/{id:[0-9]+} to collide")
+	}
+	topic := router.NewRouter[int](router.WithTopicFilters())
+	topic.Set("sport/+/score", 0)
+	if err := Add(make(Paths), "GET", topic); err == nil {
+		t.Error("expected topic filters to be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package router
+
+import "strings"
+
+type config struct {
+	sep       byte
+	noLeading bool
+	topic     bool
+	fast      bool
+	multi     bool
+}
+
+// Option configures a Router on construction.
+type Option func(*config)
+
+// WithSeparator sets the byte separating segments of a key, '/' by default.
+// Params stop at the separator, so "sensor.{room}.temperature" can be routed
+// with WithSeparator('.').
+func WithSeparator(sep byte) Option {
+	return func(c *config) {
+		c.sep = sep
+	}
+}
+
+// WithoutLeadingSeparator allows patterns and keys that don't begin with
+// the separator, like "devices/{id}/status".
+func WithoutLeadingSeparator() Option {
+	return func(c *config) {
+		c.noLeading = true
+	}
+}
+
+// WithTopicFilters switches the router to MQTT topic filter syntax: patterns
+// are split by '/', '+' matches exactly one level and a trailing '#' matches
+// the parent level and any number of levels below it. Leading wildcards don't
+// match topics starting with '$', and topics containing wildcards never match.
+// The '{name}' syntax is not available in this mode, use GetAllMatches to
+// collect every subscription matching a published topic.
+func WithTopicFilters() Option {
+	return func(c *config) {
+		c.sep = '/'
+		c.noLeading = true
+		c.topic = true
+	}
+}
+
+// WithoutBacktracking restores the fast path of matching, where a param
+// followed by a suffix, like "{name}.tar.gz", only tries the first occurrence
+// of the suffix in the segment. By default every occurrence is tried until
+// the rest of the pattern matches, so "a.tar.gz.tar.gz" is matched with name
+// "a.tar.gz". Siblings are tried in order either way. Wildcards are only
+// allowed at the end of patterns without backtracking. Unlike the original
+// fast path, a param never matches empty, so "/.tar.gz" doesn't match
+// "/{name}.tar.gz" in either mode.
+func WithoutBacktracking() Option {
+	return func(c *config) {
+		c.fast = true
+	}
+}
+
+// WithMultiSegmentRegex lets regex constraints match across separators, like
+// "{path:.+\\.go}". By default they match within a single segment. Either way
+// they are anchored at the start of the segment.
+func WithMultiSegmentRegex() Option {
+	return func(c *config) {
+		c.multi = true
+	}
+}
+
+// next parses the next matcher from the pattern, start tells whether the
+// path is at the beginning of the pattern.
+func (c *config) next(path string, start bool) (matcher, int, error) {
+	if c.topic {
+		return nextTopic(path, c.sep, start)
+	}
+	return next(path, c)
+}
+
+// valid reports whether the key satisfies the leading separator requirement.
+func (c *config) valid(path string) bool {
+	if c.noLeading {
+		return path != ""
+	}
+	return path != "" && path[0] == c.sep
+}
+
+// check returns the error of registering an invalid pattern, or nil.
+func (c *config) check(pattern string) error {
+	if pattern == "" {
+		return ErrEmptyPath
+	}
+	if !c.valid(pattern) {
+		return ErrInvalidPath.With(c.sep, pattern)
+	}
+	return nil
+}
+
+// routable reports whether the key can be looked up in the router.
+func (c *config) routable(path string) bool {
+	return c.valid(path) && (!c.topic || !strings.ContainsAny(path, "+#"))
+}
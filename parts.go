@@ -0,0 +1,57 @@
+package router
+
+// PartKind is the kind of a Part of a pattern.
+type PartKind int
+
+const (
+	// Literal matches Part.Value itself.
+	Literal PartKind = iota
+	// Param is a {name} param, matching a non-empty part of a segment.
+	Param
+	// Regex is a {name:regex} param, matching Part.Expr.
+	Regex
+	// Wildcard is a {name:*} param, matching any part of the path.
+	Wildcard
+	// Level is an MQTT '+', matching a single level.
+	Level
+	// MultiLevel is an MQTT '#', matching any number of levels.
+	MultiLevel
+)
+
+// Part is a piece of a pattern, as parsed by Set.
+type Part struct {
+	Kind  PartKind
+	Value string // the literal, or the name of the param
+	Expr  string // the regular expression of a Regex
+}
+
+// Parse splits the pattern into the parts Set registers it with, for tools
+// describing the routes like documentation generators. It returns the same
+// errors as Set for invalid patterns. It's routine-safe.
+func (r *Router[T]) Parse(pattern string) ([]Part, error) {
+	if err := r.check(pattern); err != nil {
+		return nil, err
+	}
+	ms, err := r.parse(pattern)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]Part, len(ms))
+	for i, m := range ms {
+		switch m := m.(type) {
+		case literal:
+			parts[i] = Part{Kind: Literal, Value: string(m)}
+		case param:
+			parts[i] = Part{Kind: Param, Value: m.key}
+		case regex:
+			parts[i] = Part{Kind: Regex, Value: m.key, Expr: m.expr}
+		case wildcard:
+			parts[i] = Part{Kind: Wildcard, Value: m.key}
+		case level:
+			parts[i] = Part{Kind: Level}
+		case multilevel:
+			parts[i] = Part{Kind: MultiLevel}
+		}
+	}
+	return parts, nil
+}
@@ -1,67 +1,274 @@
 package router
 
+import (
+	"sort"
+	"strings"
+)
+
 type Router[T any] struct {
-	tree node[T]
+	tree  node[T]
+	flat  *flat[T]
+	seq   int // registrations so far, ordering Routes
+	codec Codec[T]
+	config
+}
+
+// Route is a registered pattern along with its value.
+type Route[T any] struct {
+	Pattern  string
+	Name     string // free-form, for the application to tell routes apart
+	Priority int
+	Value    T
 }
 
 // Set registers a value for the given URL pattern. It's not routine-safe.
 func (r *Router[T]) Set(path string, handler T) error {
-	if path == "" || path[0] != '/' {
-		return ErrInvalidPath.With(path)
+	return r.SetWithPriority(path, handler, 0)
+}
+
+// SetWithPriority registers a value for the given URL pattern with a
+// priority, 0 for Set. Of the patterns matching a path, the one of the
+// highest priority wins, the default precedence of literals, params, regexes
+// and wildcards deciding between equal priorities. Registering a nonzero
+// priority fails if the pattern branches off a route of the same priority
+// where both branches are regexes, since nothing orders them.
+// It's not routine-safe.
+func (r *Router[T]) SetWithPriority(path string, handler T, priority int) error {
+	return r.SetRoute(Route[T]{Pattern: path, Priority: priority, Value: handler})
+}
+
+// SetRoute registers a route, like SetWithPriority with its name.
+// It's not routine-safe.
+func (r *Router[T]) SetRoute(route Route[T]) error {
+	if err := r.set(route); err != nil {
+		return err
 	}
-	_, err := r.tree.add(path, path, handler)
 	r.tree.sort()
-	return err
+	r.mutated()
+	return nil
+}
+
+// SetMany registers the routes like SetRoute in turn, sorting the tree once
+// at the end instead of after every route, which makes it much faster for
+// large route tables. The routes failing to register are skipped and their
+// errors returned together as Errors. It's not routine-safe.
+func (r *Router[T]) SetMany(routes ...Route[T]) error {
+	var errs Errors
+	for _, route := range routes {
+		if err := r.set(route); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.tree.sort()
+	r.mutated()
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// set registers a route, leaving the tree to sort.
+func (r *Router[T]) set(route Route[T]) error {
+	if r.flat != nil {
+		return ErrFrozen.With(route.Pattern)
+	}
+	if err := r.check(route.Pattern); err != nil {
+		return err
+	}
+	n, err := r.tree.add(route.Pattern, route.Value, route.Priority, &r.config)
+	if err != nil {
+		return err
+	}
+	r.seq++
+	n.name, n.seq = route.Name, r.seq
+	return nil
+}
+
+// Routes returns the registered routes, in the order they were registered.
+// It's routine-safe.
+func (r *Router[T]) Routes() []Route[T] {
+	var nodes []*node[T]
+	r.tree.each(func(n *node[T]) {
+		nodes = append(nodes, n)
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	routes := make([]Route[T], len(nodes))
+	for i, n := range nodes {
+		routes[i] = n.route()
+	}
+	return routes
+}
+
+// match returns the node GetParam routes the path to, or nil.
+func (r *Router[T]) match(path string) *node[T] {
+	if !r.routable(path) {
+		return nil
+	}
+	return r.tree.get(path, nil, !r.fast)
 }
 
 // GetParam matches the given path and returns the corresponding value,
 // assigning the given params map with the matched parameters.
 // If no pattern is found, the zero value is returned. It's routine-safe.
 func (r *Router[T]) GetParam(path string, params map[string]string) (zero T) {
-	if path == "" || path[0] != '/' || len(r.tree.children) == 0 {
+	if !r.routable(path) || len(r.tree.children) == 0 {
+		return zero
+	}
+	if r.flat != nil {
+		if v := r.flat.get(path, params, !r.fast); v != -1 {
+			return r.flat.values[v]
+		}
 		return zero
 	}
 	n := &r.tree
-	if ch := n.children[0]; ch.b == '/' {
+	if ch := n.children[0]; !r.noLeading && ch.b == r.sep {
 		// first node is almost always a literal("/")
 		m := ch.m.(literal)
 		end, ok := 1, len(m) == 1
 		if !ok {
 			end, _, ok = m.match(path)
 		}
-		if ok {
+		if ok && (len(path) > end || ch.maxprio == ch.minprio) {
 			n, path = ch, path[end:]
 			if path == "" {
 				zero = n.handler
 			}
 		}
 	}
-	n = n.get(path, params)
+	n = n.get(path, params, !r.fast)
 	if n == nil {
 		return zero
 	}
 	return n.handler
 }
 
+// GetAllMatches calls f with the value of every pattern matching the path,
+// until f returns false. The values come in MostSpecific order.
+// It's routine-safe.
 func (r *Router[T]) GetAllMatches(path string, f func(T) (more bool)) {
-	if path == "" || path[0] != '/' || len(r.tree.children) == 0 || f == nil {
+	if !r.routable(path) || f == nil {
 		return
 	}
-	n := &r.tree
-	if ch := n.children[0]; ch.b == '/' {
-		// first node is almost always a literal("/")
-		m := ch.m.(literal)
-		end, ok := 1, len(m) == 1
-		if !ok {
-			end, _, ok = m.match(path)
+	if r.tree.maxprio == r.tree.minprio {
+		r.tree.getall(path, nil, &walkAll[T]{search: search{backtrack: !r.fast}}, func(n *node[T], _ []string) bool {
+			return f(n.handler)
+		})
+		return
+	}
+	var nodes []*node[T]
+	r.tree.getall(path, nil, &walkAll[T]{search: search{backtrack: !r.fast}}, func(n *node[T], _ []string) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].priority > nodes[j].priority
+	})
+	for _, n := range nodes {
+		if !f(n.handler) {
+			return
 		}
-		if ok {
-			n, path = ch, path[end:]
+	}
+}
+
+// Match describes a pattern matching a path.
+type Match[T any] struct {
+	Pattern string
+	Value   T
+	Params  map[string]string
+}
+
+// Order is the order in which GetAllMatchesOrdered reports matches.
+type Order int
+
+const (
+	// MostSpecific reports matches in the order Get prefers them: higher
+	// priorities come first, then among equal priorities literals come
+	// before params, params before regexes and regexes before wildcards at
+	// every branch, and longer patterns come before their prefixes. The
+	// first match is the one Get returns.
+	MostSpecific Order = iota
+	// LeastSpecific is the reverse of MostSpecific.
+	LeastSpecific
+)
+
+// GetAllMatchesOrdered calls f with every pattern matching the path, along
+// with its value and params, in the given order until f returns false.
+// It's routine-safe.
+func (r *Router[T]) GetAllMatchesOrdered(path string, order Order, f func(Match[T]) (more bool)) {
+	if !r.routable(path) || f == nil {
+		return
+	}
+	var matches []Match[T]
+	var prios []int
+	stream := order == MostSpecific && r.tree.maxprio == r.tree.minprio
+	r.tree.getall(path, make([]string, 0, 8), &walkAll[T]{search: search{backtrack: !r.fast}}, func(n *node[T], params []string) bool {
+		m := Match[T]{Pattern: n.pattern, Value: n.handler, Params: make(map[string]string, len(params)/2)}
+		for i := len(params) - 2; i >= 0; i -= 2 { // outer params win, as in GetParam
+			m.Params[params[i]] = strings.Clone(params[i+1])
+		}
+		if stream {
+			return f(m)
 		}
+		matches, prios = append(matches, m), append(prios, n.priority)
+		return true
+	})
+	if stream {
+		return
 	}
-	if !n.getcb(path, f) && path == "" {
-		f(n.handler)
+	sort.Stable(byPriority[T]{matches, prios})
+	if order == MostSpecific {
+		for _, m := range matches {
+			if !f(m) {
+				return
+			}
+		}
+		return
 	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		if !f(matches[i]) {
+			return
+		}
+	}
+}
+
+// byPriority sorts matches by their priorities, the highest first.
+type byPriority[T any] struct {
+	matches []Match[T]
+	prios   []int
+}
+
+func (b byPriority[T]) Len() int           { return len(b.matches) }
+func (b byPriority[T]) Less(i, j int) bool { return b.prios[i] > b.prios[j] }
+func (b byPriority[T]) Swap(i, j int) {
+	b.matches[i], b.matches[j] = b.matches[j], b.matches[i]
+	b.prios[i], b.prios[j] = b.prios[j], b.prios[i]
+}
+
+// GetAllPaths calls f with every registered key consisting of literals only
+// that matches the given pattern, until f returns false. It's the inverse of
+// GetAllMatches, the pattern is parsed with the same syntax as Set.
+// It's routine-safe.
+func (r *Router[T]) GetAllPaths(pattern string, f func(path string, value T) (more bool)) error {
+	if err := r.check(pattern); err != nil {
+		return err
+	}
+	var m node[struct{}]
+	if _, err := m.add(pattern, struct{}{}, 0, &r.config); err != nil {
+		return err
+	}
+	prefix := ""
+	if l, ok := m.children[0].m.(literal); ok {
+		prefix = string(l)
+	}
+	r.tree.paths(nil, prefix, func(key string, n *node[T]) bool {
+		if m.get(key, nil, !r.fast) == nil {
+			return true
+		}
+		return f(key, n.handler)
+	})
+	return nil
 }
 
 // Get matches the given path and returns the corresponding value.
@@ -70,6 +277,22 @@ func (r *Router[T]) Get(path string) T {
 	return r.GetParam(path, nil)
 }
 
-func NewRouter[T any]() *Router[T] {
-	return &Router[T]{tree: node[T]{m: literal("")}}
+// Freeze compacts the registered patterns into contiguous arrays for faster
+// lookups by Get and GetParam, which pays off with large route tables. The
+// tree stays alongside, since Routes, GetAllMatches, MarshalBinary, Diff and
+// the other methods walking it use it still. Registering patterns afterwards
+// fails with ErrFrozen. It's not routine-safe.
+func (r *Router[T]) Freeze() {
+	if r.flat == nil {
+		r.flat = newFlat(&r.tree)
+	}
+}
+
+// NewRouter creates a Router, by default routing URL paths separated by '/'.
+func NewRouter[T any](opts ...Option) *Router[T] {
+	r := &Router[T]{tree: node[T]{m: literal("")}, config: config{sep: '/'}}
+	for _, opt := range opts {
+		opt(&r.config)
+	}
+	return r
 }
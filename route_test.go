@@ -1,10 +1,14 @@
 package router
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRouterBasic(t *testing.T) {
@@ -33,6 +37,641 @@ func TestRouterOrder(t *testing.T) {
 	}
 }
 
+func TestRouterSplitLiteral(t *testing.T) {
+	r := NewRouter[string]()
+	routes := [...]string{"/ab", "/ac", "/a"}
+	for _, route := range routes {
+		if err := r.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+	for _, route := range routes {
+		if got := r.Get(route); got != route {
+			t.Errorf("expected %q to match itself, got %q", route, got)
+		}
+	}
+}
+
+func TestRouterSeparator(t *testing.T) {
+	r := NewRouter[int](WithSeparator('.'), WithoutLeadingSeparator())
+	r.Set("sensor.{room}.temperature", 1)
+	r.Set("sensor.{room}.{metric:*}", 2)
+	r.Set("sensor/{room}", 3)
+	params := make(map[string]string)
+	if hit := r.GetParam("sensor.kitchen.temperature", params); hit != 1 || params["room"] != "kitchen" {
+		t.Errorf("expected to match 1 with room kitchen, got %d with %v", hit, params)
+	}
+	if hit := r.Get("sensor.kitchen.humidity.max"); hit != 2 {
+		t.Errorf("expected to match 2, got %d", hit)
+	}
+	if hit := r.Get("sensor/a/b"); hit != 3 {
+		t.Errorf("expected '/' to be part of the param, got %d", hit)
+	}
+	if hit := r.Get("sensor.a/b.temperature"); hit != 1 {
+		t.Errorf("expected '/' to be part of the param, got %d", hit)
+	}
+	if err := r.Set("", 4); err == nil || err.Error() != "path must not be empty" {
+		t.Errorf("expected empty path error, got %v", err)
+	}
+
+	r = NewRouter[int](WithSeparator('.'))
+	if err := r.Set("sensor.{room}", 1); err == nil || err.Error() != "path must begin with '.' in path 'sensor.{room}'" {
+		t.Errorf("expected leading separator error, got %v", err)
+	}
+	if err := r.Set(".sensor.{room}", 1); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if hit := r.Get(".sensor.a"); hit != 1 {
+		t.Errorf("expected to match 1, got %d", hit)
+	}
+}
+
+func TestTopicFilters(t *testing.T) {
+	r := NewRouter[string](WithTopicFilters())
+	filters := [...]string{
+		"sport/tennis/player1",
+		"sport/tennis/player1/#",
+		"sport/#",
+		"sport/+",
+		"sport/+/player1",
+		"+/+",
+		"/+",
+		"+",
+		"#",
+		"$SYS/#",
+		"$SYS/monitor/+",
+		"/a/b",
+		"/a/c",
+		"/a/#",
+	}
+	for _, filter := range filters {
+		if err := r.Set(filter, filter); err != nil {
+			t.Fatalf("unexpected error for filter '%s': %v", filter, err)
+		}
+	}
+	for topic, want := range map[string][]string{
+		"sport":                        {"sport/#", "+", "#"},
+		"sport/":                       {"sport/#", "sport/+", "+/+", "#"},
+		"sport/tennis/player1":         {"sport/tennis/player1", "sport/tennis/player1/#", "sport/#", "sport/+/player1", "#"},
+		"sport/tennis/player1/ranking": {"sport/tennis/player1/#", "sport/#", "#"},
+		"/finance":                     {"+/+", "/+", "#"},
+		"$SYS/monitor/Clients":         {"$SYS/#", "$SYS/monitor/+"},
+		"$SYS":                         {"$SYS/#"},
+		"sport/+":                      nil,
+		"sport/#":                      nil,
+	} {
+		var got []string
+		r.GetAllMatches(topic, func(hit string) bool {
+			got = append(got, hit)
+			return true
+		})
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("topic '%s' matched %v, want %v", topic, got, want)
+		}
+	}
+
+	for _, filter := range [...]string{"sport+", "sport/+tennis", "sport/#/ranking", "sport#"} {
+		if err := r.Set(filter, filter); err == nil {
+			t.Errorf("expected error for invalid filter '%s'", filter)
+		}
+	}
+}
+
+func TestRouterPriority(t *testing.T) {
+	r := NewRouter[string]()
+	r.Set("/users/{id}", "param")
+	if err := r.SetWithPriority("/users/{id:[0-9]+}", "regex", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Set("/users/admin", "admin")
+	r.Set("/users/bob", "bob")
+	for path, want := range map[string]string{
+		"/users/42":    "regex",
+		"/users/alice": "param",
+		"/users/bob":   "bob",
+		"/users/admin": "admin",
+	} {
+		if hit := r.Get(path); hit != want {
+			t.Errorf("expected %s to match %s, got %s", path, want, hit)
+		}
+	}
+
+	// the default precedence orders different kinds of the same priority
+	for _, route := range [...]string{"/users/{id}/x", "/users/me"} {
+		if err := r.SetWithPriority(route, route, 1); err != nil {
+			t.Errorf("SetWithPriority(%q): %v", route, err)
+		}
+	}
+	if err := r.SetWithPriority("/users/{name:[a-z]+}", "name", 1); err == nil || !strings.Contains(err.Error(), "same priority 1") {
+		t.Errorf("expected priority conflict between regexes, got %v", err)
+	}
+	if err := r.SetWithPriority("/users/{name:[a-z]+}", "name", 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := r.SetWithPriority("/users/{id:[0-9]+}/x", "x", 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	r.SetWithPriority("/{path:*}", "last", -1)
+	r.SetWithPriority("/{v:v[0-9]+}", "version", -1)
+	if err := r.SetWithPriority("/{w:w.*}", "w", -1); err == nil {
+		t.Errorf("expected priority conflict for negative priorities")
+	}
+	var got []string
+	r.GetAllMatchesOrdered("/users/1", MostSpecific, func(m Match[string]) bool {
+		got = append(got, m.Value)
+		return true
+	})
+	// "/users/{id}/x" doesn't lend its priority to "/users/{id}"
+	if want := []string{"regex", "param", "last"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRouterPriorityDeeperSibling(t *testing.T) {
+	r := NewRouter[string]()
+	r.Set("/users/{id:[0-9]+}", "regex")
+	r.Set("/users/{id}", "param")
+	for _, route := range [...]string{"/users/{id:[0-9]+}/x", "/users/{id}/y"} {
+		if err := r.SetWithPriority(route, route, 1); err != nil {
+			t.Fatalf("SetWithPriority(%q): %v", route, err)
+		}
+		// the deeper route doesn't reorder the siblings of its parent
+		for path, want := range map[string]string{
+			"/users/42":  "param",
+			"/users/bob": "param",
+		} {
+			if hit := r.Get(path); hit != want {
+				t.Errorf("after %s, expected %s to match %s, got %s", route, path, want, hit)
+			}
+		}
+	}
+	r.Freeze()
+	for path, want := range map[string]string{
+		"/users/42":   "param",
+		"/users/42/x": "/users/{id:[0-9]+}/x",
+		"/users/42/y": "/users/{id}/y",
+	} {
+		if hit := r.Get(path); hit != want {
+			t.Errorf("expected frozen %s to match %s, got %s", path, want, hit)
+		}
+	}
+}
+
+func TestRouterMarshalRoutes(t *testing.T) {
+	type value struct {
+		ID   int
+		Tags []string
+	}
+	routes := []Route[value]{
+		{Pattern: "/users/{id}", Name: "user", Value: value{1, []string{"a"}}},
+		{Pattern: "/users/{id:[0-9]+}", Name: "numeric", Priority: 1, Value: value{2, nil}},
+		{Pattern: "/users/me", Value: value{3, []string{"b", "c"}}},
+		{Pattern: "/regex/{c2:(?<named>extra)_alt}/{rest:*}", Value: value{4, nil}},
+		{Pattern: "/regex/{c1:big_alt|alt|small_alt}/{rest:*}", Value: value{5, nil}},
+		{Pattern: "/{path:*}", Name: "fallback", Priority: -1, Value: value{6, nil}},
+	}
+	r := NewRouter[value]()
+	for _, route := range routes {
+		if err := r.SetRoute(route); err != nil {
+			t.Fatalf("SetRoute(%q): %v", route.Pattern, err)
+		}
+	}
+	codec := Codec[value]{
+		Encode: func(v value) ([]byte, error) { return json.Marshal(v) },
+		Decode: func(b []byte) (v value, err error) {
+			err = json.Unmarshal(b, &v)
+			return v, err
+		},
+	}
+	data, err := r.MarshalRoutes(codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JSON values are embedded as is
+	if want := `"value":{"ID":1,"Tags":["a"]}`; !strings.Contains(string(data), want) {
+		t.Errorf("expected %s in %s", want, data)
+	}
+	loaded := NewRouter[value]()
+	if err := loaded.LoadRoutes(data, codec); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Routes(); !reflect.DeepEqual(got, routes) {
+		t.Errorf("loaded routes %v, want %v", got, routes)
+	}
+	checkParams(t, "loaded", r, loaded, []string{"/users/42", "/users/bob", "/users/me", "/regex/alt/x", "/regex/extra_alt/y", "/other"})
+
+	table := `[{"pattern":"/new","value":null},{"pattern":"/users/me","value":""}]`
+	if err := loaded.LoadRoutes([]byte(table), Codec[value]{Decode: func([]byte) (value, error) { return value{}, nil }}); err == nil {
+		t.Error("expected conflict loading a registered pattern")
+	}
+	if got := loaded.Routes(); !reflect.DeepEqual(got, routes) {
+		t.Errorf("failed load registered routes %v", got)
+	}
+	if err := NewRouter[value]().LoadRoutes(data, Codec[value]{}); err == nil {
+		t.Error("expected error decoding values without a codec")
+	}
+
+	// the values needn't be JSON
+	raw := NewRouter[string]()
+	raw.SetCodec(Codec[string]{
+		Encode: func(v string) ([]byte, error) { return []byte(v), nil },
+		Decode: func(b []byte) (string, error) { return string(b), nil },
+	})
+	raw.Set("/a", "not json\x00")
+	if data, err = raw.MarshalRoutes(Codec[string]{}); err != nil {
+		t.Fatal(err)
+	}
+	rawLoaded := NewRouter[string]()
+	if err := rawLoaded.LoadRoutes(data, Codec[string]{Decode: func(b []byte) (string, error) { return string(b), nil }}); err != nil {
+		t.Fatal(err)
+	}
+	if v := rawLoaded.Get("/a"); v != "not json\x00" {
+		t.Errorf("loaded %q, want %q", v, "not json\x00")
+	}
+}
+
+func TestRouterSnapshot(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts   []Option
+		routes []string
+		paths  []string
+	}{
+		"default": {
+			routes: []string{"/", "/hi", "/contact/", "/co", "/hello/{name}", "/files/{name}.tar.gz", "/{repo:*}/-/blob/{ref}",
+				"/regex/{c1:big_alt|alt|small_alt}/{rest:*}", "/regex/{c2:(?<named>extra)_alt}/{rest:*}", "/a", "/b", "/c", "/d", "/e", "/f", "/g", "/h"},
+			paths: []string{"/", "/hi", "/contact/", "/cx", "/hello/x", "/files/a.tar.gz", "/a/b/-/blob/main", "/regex/extra_alt/x", "/g"},
+		},
+		"topic": {
+			opts:   []Option{WithTopicFilters()},
+			routes: []string{"sport/tennis/player1", "sport/#", "sport/+", "+/+", "#"},
+			paths:  []string{"sport", "sport/tennis/player1", "sport/x", "$SYS/x", "a/b"},
+		},
+	} {
+		r := NewRouter[string](tc.opts...)
+		r.SetCodec(stringCodec)
+		for _, route := range tc.routes {
+			if err := r.Set(route, route); err != nil {
+				t.Fatalf("Set(%q): %v", route, err)
+			}
+		}
+		r.SetRoute(Route[string]{Pattern: tc.routes[0] + "x", Name: "named", Priority: 2, Value: "x"})
+		data, err := r.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := NewRouter[string]()
+		loaded.SetCodec(stringCodec)
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(loaded.tree, r.tree) || loaded.config != r.config {
+			t.Errorf("%s: loaded tree differs", name)
+		}
+		if got, want := loaded.Routes(), r.Routes(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: loaded routes %v, want %v", name, got, want)
+		}
+		checkParams(t, name+": loaded router", r, loaded, tc.paths)
+		for i := range data {
+			if err := loaded.UnmarshalBinary(data[:i]); err == nil {
+				t.Errorf("%s: expected error loading %d bytes of %d", name, i, len(data))
+			}
+		}
+	}
+
+	r := NewRouter[string]()
+	r.SetCodec(stringCodec)
+	r.Set("/a", "a")
+	r.Freeze()
+	data, _ := r.MarshalBinary()
+	loaded := NewRouter[string]()
+	loaded.SetCodec(stringCodec)
+	if err := loaded.UnmarshalBinary(data); err != nil || loaded.flat == nil || loaded.Get("/a") != "a" {
+		t.Errorf("expected a frozen router, got %v", err)
+	}
+	data[len(snapshotMagic)] = snapshotVersion + 1
+	if err := loaded.UnmarshalBinary(data); err == nil || err.Error() != "invalid snapshot: unsupported version 3" {
+		t.Errorf("expected version error, got %v", err)
+	}
+	if _, err := NewRouter[int]().MarshalBinary(); err != nil {
+		t.Errorf("expected an empty router to need no codec, got %v", err)
+	}
+	ints := NewRouter[int]()
+	ints.Set("/a", 1)
+	if _, err := ints.MarshalBinary(); err == nil {
+		t.Error("expected error without codec")
+	}
+}
+
+func TestRouterSetMany(t *testing.T) {
+	routes := []Route[string]{
+		{Pattern: "/", Value: "/"},
+		{Pattern: "/hello/{name}", Value: "/hello/{name}"},
+		{Pattern: "/hello/test", Value: "/hello/test"},
+		{Pattern: "/users/{id:[0-9]+}", Name: "user", Priority: 1, Value: "/users/{id:[0-9]+}"},
+		{Pattern: "/users/{name}", Value: "/users/{name}"},
+		{Pattern: "/{path:*}", Priority: -1, Value: "/{path:*}"},
+		{Pattern: "/hello/{other}", Value: "conflict"},
+		{Pattern: "invalid", Value: "invalid"},
+		{Pattern: "/hello/test", Value: "duplicate"},
+	}
+	one, many := NewRouter[string](), NewRouter[string]()
+	for _, route := range routes {
+		one.SetRoute(route)
+	}
+	err := many.SetMany(routes...)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	for i, want := range []string{"conflicts with existing wildcard", "must begin with '/'", "already registered"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("error %d: expected %q, got %v", i, want, errs[i])
+		}
+	}
+	if !reflect.DeepEqual(one.Routes(), many.Routes()) {
+		t.Errorf("SetMany registered %v, want %v", many.Routes(), one.Routes())
+	}
+	checkParams(t, "SetMany", one, many, []string{"/", "/hello/test", "/hello/x", "/users/42", "/users/bob", "/other/x"})
+	if err := many.SetMany(Route[string]{Pattern: "/new", Value: "/new"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterTx(t *testing.T) {
+	r := NewRouter[string]()
+	for _, route := range []string{"/", "/hello/{name}", "/users/{id}", "/{path:*}"} {
+		r.Set(route, route)
+	}
+	before := r.Routes()
+
+	tx := r.Begin()
+	tx.Set("/hello/test", "/hello/test")
+	tx.Delete("/users/{id}")
+	tx.Set("/users/{id:[0-9]+}", "/users/{id:[0-9]+}")
+	tx.Delete("/missing")
+	tx.Set("/static/{path:*}/x", "/static/{path:*}/x")
+	err := tx.Commit()
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || !strings.Contains(errs[0].Error(), "'/missing'") {
+		t.Fatalf("expected the missing route to fail, got %v", err)
+	}
+	if !reflect.DeepEqual(r.Routes(), before) || r.Get("/hello/test") != "/hello/{name}" {
+		t.Errorf("failed commit changed the router: %v", r.Routes())
+	}
+
+	tx.Set("/hello/test", "/hello/test")
+	tx.Delete("/users/{id}")
+	tx.SetRoute(Route[string]{Pattern: "/users/{id:[0-9]+}", Name: "user", Value: "/users/{id:[0-9]+}"})
+	tx.Delete("/hello/{name}")
+	tx.Set("/hello/{who}", "/hello/{who}")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	checkRequests(t, r, testRequests{
+		{"/hello/test", false, "/hello/test", nil},
+		{"/hello/x", false, "/hello/{who}", map[string]string{"who": "x"}},
+		{"/users/42", false, "/users/{id:[0-9]+}", map[string]string{"id": "42"}},
+		{"/users/bob", false, "/{path:*}", map[string]string{"path": "users/bob"}},
+	})
+	if err := r.Begin().Commit(); err != nil {
+		t.Errorf("expected an empty transaction to commit, got %v", err)
+	}
+
+	tx.Set("/x/{a}", "a")
+	tx.Set("/x/{b}", "b")
+	if err := tx.Commit(); err == nil || r.Get("/x/1") != "/{path:*}" {
+		t.Errorf("expected conflicting routes to fail, got %v", err)
+	}
+	r.Freeze()
+	tx.Set("/new", "/new")
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "frozen") {
+		t.Errorf("expected frozen error, got %v", err)
+	}
+}
+
+func TestRouterTxPrune(t *testing.T) {
+	r := NewRouter[string]()
+	r.Set("/abc", "/abc")
+	tx := r.Begin()
+	tx.Set("/abd/{x}", "/abd/{x}")
+	tx.Delete("/abd/{x}")
+	tx.Set("/abe", "/abe")
+	tx.Delete("/missing")
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected the missing route to fail")
+	}
+	// the literal split for the undone routes is joined again
+	if len(r.tree.children) != 1 || r.tree.children[0].m != literal("/abc") || len(r.tree.children[0].children) != 0 {
+		t.Errorf("failed commit left the tree split: %q", r.tree.children[0].m)
+	}
+
+	tx.Delete("/abc")
+	tx.Set("/{id}", "/{id}")
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx.Delete("/{id}")
+	tx.Set("/{name}", "/{name}")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("expected the deleted param to make room, got %v", err)
+	}
+	checkRequests(t, r, testRequests{
+		{"/abc", false, "/{name}", map[string]string{"name": "abc"}},
+	})
+}
+
+func TestRouterSetAtomic(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts   []Option
+		routes []Route[string]
+		fail   []Route[string]
+	}{
+		"default": {
+			routes: []Route[string]{
+				{Pattern: "/hello/{name}"}, {Pattern: "/hello/test"}, {Pattern: "/contact/us"},
+				{Pattern: "/users/{id:[0-9]+}", Priority: 1}, {Pattern: "/files/{path:*}"},
+			},
+			fail: []Route[string]{
+				{Pattern: "hello"},
+				{Pattern: "/new/branch/{bad"},
+				{Pattern: "/contact/{x}{y}"},
+				{Pattern: "/cont/{bad:(}"},
+				{Pattern: "/hello/{other}/x"},
+				{Pattern: "/hello/test"},
+				{Pattern: "/users/{name:[a-z]+}", Priority: 1},
+				{Pattern: "/users/{hex:[0-9a-f]+}/x", Priority: 1},
+			},
+		},
+		"fast": {
+			opts:   []Option{WithoutBacktracking()},
+			routes: []Route[string]{{Pattern: "/static/{path:*}"}, {Pattern: "/stat"}},
+			fail:   []Route[string]{{Pattern: "/stats/{path:*}/other"}, {Pattern: "/st/{a:*}/b"}},
+		},
+	} {
+		r := NewRouter[string](tc.opts...)
+		r.SetCodec(stringCodec)
+		if err := r.SetMany(tc.routes...); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, route := range tc.fail {
+			before, _ := r.MarshalBinary()
+			if err := r.SetRoute(route); err == nil {
+				t.Fatalf("%s: expected error registering '%s'", name, route.Pattern)
+			}
+			if after, _ := r.MarshalBinary(); string(before) != string(after) {
+				t.Errorf("%s: failing to register '%s' changed the tree", name, route.Pattern)
+			}
+		}
+	}
+
+	m := NewMultiRouter[string]()
+	m.Add("/events/{name}", "log")
+	tree := m.r.tree.children[0]
+	if err := m.Add("/events/x/{bad", "bad"); err == nil || len(tree.children) != 1 || tree.m != literal("/events/") {
+		t.Errorf("failing to add changed the tree, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, b := NewRouter[int](), NewRouter[int]()
+	a.Set("/users/{id}", 1)
+	a.Set("/files/{path:*}", 2)
+	a.Set("/about", 3)
+	b.Set("/users/{id}", 1)
+	b.Set("/files/{path:*}", 20)
+	b.Set("/users/me", 4)
+	b.Set("/v{n:[0-9]+}/x", 5)
+
+	route := func(pattern string, value int) Route[int] {
+		return Route[int]{Pattern: pattern, Value: value}
+	}
+	want := []Change[int]{
+		{Kind: Modified, Old: route("/files/{path:*}", 2), New: route("/files/{path:*}", 20)},
+		{Kind: Removed, Old: route("/about", 3)},
+		{Kind: Added, New: route("/users/me", 4)},
+		{Kind: Added, New: route("/v{n:[0-9]+}/x", 5)},
+		{Kind: Rerouted, Path: "/about", Old: route("/about", 3)},
+		{Kind: Rerouted, Path: "/users/me", Old: route("/users/{id}", 1), New: route("/users/me", 4)},
+		{Kind: Rerouted, Path: "/v0/x", New: route("/v{n:[0-9]+}/x", 5)},
+	}
+	if got := Diff(a, b, func(x, y int) bool { return x == y }); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v\nwant %+v", got, want)
+	}
+	if got := Diff(a, a, nil); len(got) != 0 {
+		t.Errorf("expected no difference with itself, got %+v", got)
+	}
+}
+
+func TestRouterExamples(t *testing.T) {
+	r := NewRouter[int]()
+	r.Set("/users/{id}", 1)
+	r.Set("/users/x", 2)
+	r.Set("/files/{name}.tar.gz", 3)
+	r.Set("/v{n:[0-9]{2}}/{rest:*}", 4)
+	r.Set("/a/{id}", 5)
+	r.SetWithPriority("/a/{path:*}", 6, 1)
+
+	cases := []struct {
+		pattern string
+		want    string
+	}{
+		{"/users/{id}", "/users/1"},
+		{"/users/x", "/users/x"},
+		{"/files/{name}.tar.gz", "/files/x.tar.gz"},
+		{"/v{n:[0-9]{2}}/{rest:*}", "/v00/x"},
+		{"/a/{path:*}", "/a/x"},
+	}
+	for _, c := range cases {
+		paths, err := r.Examples(c.pattern)
+		if err != nil {
+			t.Errorf("Examples(%q): %v", c.pattern, err)
+			continue
+		}
+		if paths[0] != c.want {
+			t.Errorf("Examples(%q) = %q, want %q first", c.pattern, paths, c.want)
+		}
+		for _, path := range paths {
+			if n := r.match(path); n == nil || n.pattern != c.pattern {
+				t.Errorf("example %q of %q isn't routed to it", path, c.pattern)
+			}
+		}
+	}
+	if _, err := r.Examples("/a/{id}"); err == nil || err.Error() != ErrUnreachable.With("/a/{id}").Error() {
+		t.Errorf("expected /a/{id} to be unreachable, got %v", err)
+	}
+	if _, err := r.Examples("/missing"); err == nil || err.Error() != ErrNotFound.With("/missing").Error() {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	all, err := r.AllExamples()
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Errorf("expected a single unreachable route, got %v", err)
+	}
+	if len(all) != len(cases) {
+		t.Errorf("expected examples for %d patterns, got %v", len(cases), all)
+	}
+}
+
+func TestRouterFreeze(t *testing.T) {
+	routes := [...]string{
+		"/",
+		"/hi",
+		"/contact/",
+		"/co",
+		"/c",
+		"/α",
+		"/hello/test",
+		"/hello/{name}",
+		"/user_{name}/about",
+		"/files/{name}.tar.gz",
+		"/regex/{c1:big_alt|alt|small_alt}/{rest:*}",
+		"/regex/{c2:(?<named>extra)_alt}/{rest:*}",
+		"/regex/{path:*}",
+		"/{repo:*}/-/blob/{ref}",
+		"/api", "/blog", "/docs", // enough literals for a jump table
+	}
+	paths := [...]string{
+		"/", "/hi", "/contact", "/contact/", "/co", "/c", "/cx", "/α", "/β", "/hello/test", "/hello/tes",
+		"/hello/test/", "/user_gopher/about", "/user_/about", "/files/a.tar.gz.tar.gz",
+		"/regex/alt/x", "/regex/extra_alt/x/y", "/regex/other", "/a/b/-/blob/main", "invalid",
+		"/api", "/apis", "/blog", "/docs/-/blob/x", "/e",
+	}
+	tree, frozen := NewRouter[string](), NewRouter[string]()
+	for _, route := range routes {
+		tree.Set(route, route)
+		frozen.Set(route, route)
+	}
+	frozen.SetWithPriority("/hello/{name:t.*}", "/hello/{name:t.*}", 1)
+	tree.SetWithPriority("/hello/{name:t.*}", "/hello/{name:t.*}", 1)
+	frozen.Freeze()
+	if frozen.flat.nodes[1].index == -1 {
+		t.Error("expected a jump table for the literals after '/'")
+	}
+	checkParams(t, "frozen router", tree, frozen, paths[:])
+	if err := frozen.Set("/new", "/new"); err == nil || err.Error() != "the router is frozen, cannot register path '/new'" {
+		t.Errorf("expected frozen error, got %v", err)
+	}
+}
+
+func TestRouterMultibyteLiterals(t *testing.T) {
+	// "α" and "β" share their first byte
+	routes := [...]string{"/α", "/β", "/αβ"}
+	r := NewRouter[string]()
+	for _, route := range routes {
+		if err := r.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+	r.Freeze()
+	for _, route := range routes {
+		if got := r.Get(route); got != route {
+			t.Errorf("expected %q to match itself, got %q", route, got)
+		}
+	}
+}
+
 func TestGetAllMatches(t *testing.T) {
 	r := NewRouter[int]()
 	r.Set("/{path:*}", 1)
@@ -51,6 +690,424 @@ func TestGetAllMatches(t *testing.T) {
 	}
 }
 
+func TestGetAllMatchesAllocs(t *testing.T) {
+	r := NewRouter[int]()
+	for i, route := range []string{
+		"/users/{id}", "/users/{id}/posts/{pid}", "/users/me/posts/{pid}",
+		"/files/{name}.tar.gz", "/static/{path:*}", "/v{n:[0-9]+}/x",
+	} {
+		if err := r.Set(route, i); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+	for _, path := range []string{"/users/1/posts/2", "/users/me/posts/2", "/files/a.tar.gz", "/static/a/b", "/v1/x"} {
+		cnt := 0
+		allocs := testing.AllocsPerRun(100, func() {
+			r.GetAllMatches(path, func(int) bool {
+				cnt++
+				return true
+			})
+		})
+		if cnt == 0 || allocs != 0 {
+			t.Errorf("GetAllMatches(%q): %d matches with %v allocs, want some with 0", path, cnt, allocs)
+		}
+	}
+}
+
+func TestGetAllPaths(t *testing.T) {
+	r := NewRouter[int]()
+	r.Set("/devices/1/events/boot", 1)
+	r.Set("/devices/1/events/boot/failed", 2)
+	r.Set("/devices/2/events", 3)
+	r.Set("/devices/2/status", 4)
+	r.Set("/devices/{id}/events/{rest:*}", 5)
+	r.Set("/dev", 6)
+
+	var got []string
+	if err := r.GetAllPaths("/devices/{id}/events/{rest:*}", func(path string, hit int) bool {
+		got = append(got, path)
+		return true
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"/devices/1/events/boot", "/devices/1/events/boot/failed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	cnt := 0
+	r.GetAllPaths("/{path:*}", func(path string, hit int) bool {
+		cnt++
+		return cnt < 3
+	})
+	if cnt != 3 {
+		t.Errorf("expected to stop after 3 paths, got %d", cnt)
+	}
+
+	if err := r.GetAllPaths("/{}", func(string, int) bool { return true }); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+
+	topics := NewRouter[int](WithTopicFilters())
+	topics.Set("sport", 1)
+	topics.Set("sport/tennis", 2)
+	topics.Set("sport/+", 3)
+	topics.Set("$SYS/info", 4)
+	got = got[:0]
+	topics.GetAllPaths("sport/#", func(path string, hit int) bool {
+		got = append(got, path)
+		return true
+	})
+	if want := []string{"sport", "sport/tennis"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestGetAllMatchesOrdered(t *testing.T) {
+	r := NewRouter[int]()
+	r.Set("/{path:*}", 1)
+	r.Set("/ab{var}", 2)
+	r.Set("/absolute", 3)
+	r.Set("/{reg:.*}", 4)
+	r.Set("/abs{var}", 5)
+	r.Set("/abs{var:*}", 6)
+	r.Set("/{name}", 7)
+
+	want := []Match[int]{
+		{"/absolute", 3, map[string]string{}},
+		{"/abs{var}", 5, map[string]string{"var": "olute"}},
+		{"/abs{var:*}", 6, map[string]string{"var": "olute"}},
+		{"/ab{var}", 2, map[string]string{"var": "solute"}},
+		{"/{name}", 7, map[string]string{"name": "absolute"}},
+		{"/{reg:.*}", 4, map[string]string{"reg": "absolute"}},
+		{"/{path:*}", 1, map[string]string{"path": "absolute"}},
+	}
+	var got []Match[int]
+	r.GetAllMatchesOrdered("/absolute", MostSpecific, func(m Match[int]) bool {
+		got = append(got, m)
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if hit := r.Get("/absolute"); hit != got[0].Value {
+		t.Errorf("expected the most specific match to be %d, got %d", hit, got[0].Value)
+	}
+
+	got = got[:0]
+	r.GetAllMatchesOrdered("/absolute", LeastSpecific, func(m Match[int]) bool {
+		got = append(got, m)
+		return len(got) < 2
+	})
+	if want := []Match[int]{want[6], want[5]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	var values []int
+	r.GetAllMatches("/absolute", func(hit int) bool {
+		values = append(values, hit)
+		return true
+	})
+	if want := []int{3, 5, 6, 2, 7, 4, 1}; !reflect.DeepEqual(values, want) {
+		t.Errorf("expected GetAllMatches in order %v, got %v", want, values)
+	}
+}
+
+func TestTreeBacktracking(t *testing.T) {
+	routes := [...]string{
+		"/files/{name}.tar.gz",
+		"/files/{name}.tar.gz/raw",
+		"/gz/{name}.gz",
+		"/ab{var}",
+		"/abs{var}/x",
+		"/v/{major}.x/{rest:*}",
+		"/v/{any:[^/]+}/y",
+		"/d/{a}.{b:*}",
+	}
+	tree := NewRouter[string]()
+	for _, route := range routes {
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/files/a.tar.gz", false, "/files/{name}.tar.gz", map[string]string{"name": "a"}},
+		{"/files/a.tar.gz.tar.gz", false, "/files/{name}.tar.gz", map[string]string{"name": "a.tar.gz"}},
+		{"/files/a.tar.gz.tar.gz/raw", false, "/files/{name}.tar.gz/raw", map[string]string{"name": "a.tar.gz"}},
+		{"/gz/a.gz.tar.gz.gz", false, "/gz/{name}.gz", map[string]string{"name": "a.gz.tar.gz"}},
+		{"/gz/.gz.gz", false, "/gz/{name}.gz", map[string]string{"name": ".gz"}},
+		{"/gz/.gz", true, "", nil},
+		{"/files/a.tar.gz/", true, "", nil},
+		{"/absolute", false, "/ab{var}", map[string]string{"var": "solute"}},
+		{"/absolute/x", false, "/abs{var}/x", map[string]string{"var": "olute"}},
+		{"/v/1.x.x/a/b", false, "/v/{major}.x/{rest:*}", map[string]string{"major": "1.x", "rest": "a/b"}},
+		{"/v/1.x/y", false, "/v/{major}.x/{rest:*}", map[string]string{"major": "1", "rest": "y"}},
+		{"/v/1.y/y", false, "/v/{any:[^/]+}/y", map[string]string{"any": "1.y"}},
+		{"/d/x.y.z", false, "/d/{a}.{b:*}", map[string]string{"a": "x", "b": "y.z"}},
+	})
+
+	fast := NewRouter[string](WithoutBacktracking())
+	for _, route := range routes {
+		fast.Set(route, route)
+	}
+	checkRequests(t, fast, testRequests{
+		{"/files/a.tar.gz", false, "/files/{name}.tar.gz", map[string]string{"name": "a"}},
+		{"/files/a.tar.gz.tar.gz", true, "", nil},
+		{"/gz/.gz", true, "", nil},
+		{"/absolute/x", false, "/abs{var}/x", map[string]string{"var": "olute"}},
+	})
+
+	cnt := 0
+	tree.GetAllMatches("/d/x.y.z", func(string) bool {
+		cnt++
+		return true
+	})
+	if cnt != 1 {
+		t.Errorf("expected every pattern to be reported once, got %d", cnt)
+	}
+}
+
+func TestTreeMultipleParams(t *testing.T) {
+	tree := NewRouter[string]()
+
+	routes := [...]string{
+		"/lazy/{file}.{ext}",
+		"/greedy/{file+}.{ext}",
+		"/range/{from}-{to}",
+		"/v{major}.{minor}",
+		"/v{major}.{minor}/{rest:*}",
+		"/pkg/{name+}-{version}.tar.gz",
+	}
+	for _, route := range routes {
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/lazy/a.tar.gz", false, "/lazy/{file}.{ext}", map[string]string{"file": "a", "ext": "tar.gz"}},
+		{"/greedy/a.tar.gz", false, "/greedy/{file+}.{ext}", map[string]string{"file": "a.tar", "ext": "gz"}},
+		{"/greedy/a.gz", false, "/greedy/{file+}.{ext}", map[string]string{"file": "a", "ext": "gz"}},
+		{"/greedy/.gz", true, "", nil},
+		{"/greedy/a.", true, "", nil},
+		{"/range/1-10", false, "/range/{from}-{to}", map[string]string{"from": "1", "to": "10"}},
+		{"/range/-1-10", false, "/range/{from}-{to}", map[string]string{"from": "-1", "to": "10"}},
+		{"/v1.2", false, "/v{major}.{minor}", map[string]string{"major": "1", "minor": "2"}},
+		{"/v1.2/x/y", false, "/v{major}.{minor}/{rest:*}", map[string]string{"major": "1", "minor": "2", "rest": "x/y"}},
+		{"/v1", true, "", nil},
+		{"/pkg/go-router-1.0.tar.gz", false, "/pkg/{name+}-{version}.tar.gz", map[string]string{"name": "go-router", "version": "1.0"}},
+	})
+
+	for _, route := range [...]string{"/{+}.{ext}", "/{a}{b+}"} {
+		if err := tree.Set(route, route); err == nil {
+			t.Errorf("expected error for invalid route '%s'", route)
+		}
+	}
+}
+
+func TestTreeRegexAnchored(t *testing.T) {
+	routes := [...]string{
+		"/id/{id:[0-9]+}",
+		"/json/{id:[0-9]+}.json",
+		"/alt/{x:a|ab}c",
+		"/any/{p:.+}",
+		"/go/{file:.+\\.go}",
+		"/lazy/{n:0*?}",
+	}
+	tree := NewRouter[string]()
+	multi := NewRouter[string](WithMultiSegmentRegex())
+	for _, route := range routes {
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+		multi.Set(route, route)
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/id/123", false, "/id/{id:[0-9]+}", map[string]string{"id": "123"}},
+		{"/id/abc123", true, "", nil}, // must match from the start
+		{"/id/123abc", true, "", nil},
+		{"/json/12.json", false, "/json/{id:[0-9]+}.json", map[string]string{"id": "12"}},
+		{"/alt/abc", false, "/alt/{x:a|ab}c", map[string]string{"x": "ab"}}, // backtracks to the longer alternative
+		{"/alt/ac", false, "/alt/{x:a|ab}c", map[string]string{"x": "a"}},
+		{"/any/a", false, "/any/{p:.+}", map[string]string{"p": "a"}},
+		{"/any/a/b", true, "", nil}, // single segment by default
+		{"/go/main.go", false, "/go/{file:.+\\.go}", map[string]string{"file": "main.go"}},
+		{"/go/cmd/main.go", true, "", nil},
+		{"/lazy/00", false, "/lazy/{n:0*?}", map[string]string{"n": "00"}}, // not the empty match
+	})
+	checkRequests(t, multi, testRequests{
+		{"/id/abc123", true, "", nil},
+		{"/any/a/b", false, "/any/{p:.+}", map[string]string{"p": "a/b"}},
+		{"/go/cmd/main.go", false, "/go/{file:.+\\.go}", map[string]string{"file": "cmd/main.go"}},
+		{"/go/cmd/main.go/x", true, "", nil},
+	})
+
+	fast := NewRouter[string](WithoutBacktracking())
+	fast.Set("/alt/{x:a|ab}c", "alt")
+	if hit := fast.Get("/alt/abc"); hit != "" {
+		t.Errorf("expected no backtracking into regex alternatives, got %s", hit)
+	}
+
+	for _, route := range [...]string{"/bad/{x:(}", "/bad/{x:)|(}"} {
+		if err := tree.Set(route, "bad"); err == nil {
+			t.Errorf("expected error for invalid regex in %s", route)
+		}
+	}
+}
+
+func TestTreeMiddleWildcard(t *testing.T) {
+	routes := [...]string{
+		"/{repo:*}/-/blob/{ref}",
+		"/{repo:*}/-/tree/{ref}/{path:*}",
+		"/files/{path:*}/raw",
+		"/lazy/{path:*?}/raw",
+		"/lazy/{path:*?}/raw/{rest:*}",
+	}
+	tree := NewRouter[string]()
+	for _, route := range routes {
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/group/project/-/blob/main", false, "/{repo:*}/-/blob/{ref}", map[string]string{"repo": "group/project", "ref": "main"}},
+		{"/a/-/blob/b/-/blob/main", false, "/{repo:*}/-/blob/{ref}", map[string]string{"repo": "a/-/blob/b", "ref": "main"}},
+		{"/group/project/-/tree/main/src/a.go", false, "/{repo:*}/-/tree/{ref}/{path:*}", map[string]string{"repo": "group/project", "ref": "main", "path": "src/a.go"}},
+		{"/group/project/-/blob/main/x", true, "", nil},
+		{"/files/a/raw/b/raw", false, "/files/{path:*}/raw", map[string]string{"path": "a/raw/b"}},
+		{"/files//raw", false, "/files/{path:*}/raw", map[string]string{"path": ""}},
+		{"/files/raw", true, "", nil},
+		{"/lazy/a/raw/b/raw", false, "/lazy/{path:*?}/raw/{rest:*}", map[string]string{"path": "a", "rest": "b/raw"}},
+		{"/lazy/a/b/raw", false, "/lazy/{path:*?}/raw", map[string]string{"path": "a/b"}},
+	})
+
+	fast := NewRouter[string](WithoutBacktracking())
+	err := fast.Set("/static/{filepath:*}/other", "")
+	if want := "wildcard routes are only allowed at the end of the path in path '/static/{filepath:*}/other'"; err == nil || err.Error() != want {
+		t.Errorf("expected error %q without backtracking, got %v", want, err)
+	}
+	if err := fast.Set("/u/{rest:*?}", "/u/{rest:*?}"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	checkRequests(t, fast, testRequests{
+		{"/u/abc", false, "/u/{rest:*?}", map[string]string{"rest": "abc"}},
+		{"/u/", false, "/u/{rest:*?}", map[string]string{"rest": ""}},
+	})
+}
+
+func TestTreeWildcardComplexity(t *testing.T) {
+	tree, frozen := NewRouter[string](), NewRouter[string]()
+	for _, route := range [...]string{"/{a:*}/{b:*}/{c:*}/z", "/l/{p:*?}/{q:*?}/{r:*?}/w", "/t/{rest:*}", "/u/{rest:*?}"} {
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+		if err := frozen.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+	frozen.Freeze()
+
+	// retrying every split of each wildcard would take hours on these paths
+	long := "/" + strings.Repeat("a/", 10000)
+	huge := strings.Repeat("x", 100000)
+	count := func(r *Router[string], path string) (n int) {
+		r.GetAllMatches(path, func(string) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	start := time.Now()
+	for _, r := range [...]*Router[string]{tree, frozen} {
+		if v := r.Get(long); v != "" {
+			t.Errorf("expected %q not to match, got %q", long, v)
+		}
+		if n := count(r, long) + count(r, "/l"+long); n != 0 {
+			t.Errorf("expected the long paths not to match, got %d matches", n)
+		}
+		if v := r.Get(long + "z"); v != "/{a:*}/{b:*}/{c:*}/z" {
+			t.Errorf("expected %q to match, got %q", long+"z", v)
+		}
+		if n := count(r, "/t/"+huge) + count(r, "/u/"+huge); n != 2 {
+			t.Errorf("expected each long path to match once, got %d matches", n)
+		}
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("matching took %v", d)
+	}
+}
+
+func TestTreeLiteralFanOut(t *testing.T) {
+	tree := NewRouter[string]()
+	for c := 'a'; c <= 'z'; c++ {
+		route := "/" + string(c) + "x/{id}"
+		if err := tree.Set(route, route); err != nil {
+			t.Fatalf("Set(%q): %v", route, err)
+		}
+	}
+	tree.Set("/{name}/{id}", "/{name}/{id}")
+	tree.Set("/{path:*}", "/{path:*}")
+
+	checkRequests(t, tree, testRequests{
+		{"/ax/1", false, "/ax/{id}", map[string]string{"id": "1"}},
+		{"/mx/1", false, "/mx/{id}", map[string]string{"id": "1"}},
+		{"/zx/1", false, "/zx/{id}", map[string]string{"id": "1"}},
+		{"/my/1", false, "/{name}/{id}", map[string]string{"name": "my", "id": "1"}},
+		{"/0x/1", false, "/{name}/{id}", map[string]string{"name": "0x", "id": "1"}},
+		{"/mx", false, "/{path:*}", map[string]string{"path": "mx"}},
+		{"/", false, "/{path:*}", map[string]string{"path": ""}},
+	})
+	var got []string
+	tree.GetAllMatches("/mx/1", func(pattern string) bool {
+		got = append(got, pattern)
+		return true
+	})
+	if want := []string{"/mx/{id}", "/{name}/{id}", "/{path:*}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllMatches = %v, want %v", got, want)
+	}
+}
+
+func TestTreeUnclosedBrace(t *testing.T) {
+	routes := [...]string{
+		"/user{",
+		"/user{name",
+		"/src/{path:*",
+		"/id/{n:[0-9]{4}",
+	}
+
+	for _, route := range routes {
+		tree := NewRouter[string]()
+		recv := tree.Set(route, route)
+
+		if recv == nil || !strings.Contains(recv.Error(), "missing closing '}'") {
+			t.Errorf("expected a missing '}' error for route '%s', got %v", route, recv)
+		}
+	}
+}
+
+// checkParams checks that got matches the paths with the same values and
+// params as want.
+func checkParams[T any](t *testing.T, what string, want, got *Router[T], paths []string) {
+	t.Helper()
+	if err := got.Check(); err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	for _, path := range paths {
+		wps, gps := make(map[string]string), make(map[string]string)
+		if v, gv := want.GetParam(path, wps), got.GetParam(path, gps); !reflect.DeepEqual(v, gv) || !reflect.DeepEqual(wps, gps) {
+			t.Errorf("%s matched %s with %v %v, want %v %v", what, path, gv, gps, v, wps)
+		}
+	}
+}
+
+// stringCodec stores the values as is.
+var stringCodec = Codec[string]{
+	Encode: func(v string) ([]byte, error) { return []byte(v), nil },
+	Decode: func(b []byte) (string, error) { return string(b), nil },
+}
+
 // Below tests are taken from fasthttp, licensed under the BSD 3-Clause License.
 type testRequests []struct {
 	path       string
@@ -60,6 +1117,9 @@ type testRequests []struct {
 }
 
 func checkRequests(t *testing.T, tree *Router[string], requests testRequests) {
+	if err := tree.Check(); err != nil {
+		t.Fatal(err)
+	}
 	for _, request := range requests {
 		params := make(map[string]string)
 		handler := tree.GetParam(request.path, params)
@@ -102,7 +1162,8 @@ func TestTreeAddAndGet(t *testing.T) {
 		"/hello/tooth",
 		"/hello/{name}",
 		"/regex/{c1:big_alt|alt|small_alt}/{rest:*}",
-		// "/regex/{c2:(?<named>extra)_alt}/{rest:*}",
+		"/regex/{c2:(?<named>extra)_alt}/{rest:*}",
+		"/regex/{c3:(?P<year>[0-9]{4})-(?P<month>[0-9]{2})(-(?P<day>[0-9]{2}))?}",
 		"/regex/{path:*}",
 		"/wildcard/sub/{rest:*}",
 		"/wildcard/{rest:*}",
@@ -132,7 +1193,9 @@ func TestTreeAddAndGet(t *testing.T) {
 		{"/regex/more_alt/hello", false, "/regex/{path:*}", map[string]string{"path": "more_alt/hello"}},
 		{"/regex/small_alt/hello", false, "/regex/{c1:big_alt|alt|small_alt}/{rest:*}", map[string]string{"c1": "small_alt", "rest": "hello"}},
 		{"/regex/small_alt/hello", false, "/regex/{c1:big_alt|alt|small_alt}/{rest:*}", map[string]string{"c1": "small_alt", "rest": "hello"}},
-		// {"/regex/extra_alt/hello", false, "/regex/{c2:(?<named>extra)_alt}/{rest:*}", map[string]string{"c2": "extra_alt", "named": "extra", "rest": "hello"}}, // named group
+		{"/regex/extra_alt/hello", false, "/regex/{c2:(?<named>extra)_alt}/{rest:*}", map[string]string{"c2": "extra_alt", "named": "extra", "rest": "hello"}}, // named group
+		{"/regex/2024-05", false, "/regex/{c3:(?P<year>[0-9]{4})-(?P<month>[0-9]{2})(-(?P<day>[0-9]{2}))?}", map[string]string{"c3": "2024-05", "year": "2024", "month": "05"}},
+		{"/regex/2024-05-17", false, "/regex/{c3:(?P<year>[0-9]{4})-(?P<month>[0-9]{2})(-(?P<day>[0-9]{2}))?}", map[string]string{"c3": "2024-05-17", "year": "2024", "month": "05", "day": "17"}},
 		{"/wildcard/sub", false, "/wildcard/{rest:*}", map[string]string{"rest": "sub"}},
 	})
 }
@@ -181,6 +1244,70 @@ func TestTreeWildcard(t *testing.T) {
 	})
 }
 
+// TestTreeFasthttpParams is ported from Test_Tree, Test_AddWithParam,
+// Test_TreeRootWildcard and TestRouterSamePrefixParamRoute of fasthttp/router,
+// without the trailing slash redirects.
+func TestTreeFasthttpParams(t *testing.T) {
+	routes := [...]string{
+		"/users/{name}",
+		"/users",
+		"/user/",
+		"/users/{name}/jobs",
+		"/users/admin",
+		"/users/{name}/proc", // sibling params must share their name here
+		"/static/{filepath:*}",
+		"/data/orders",
+		"/test",
+		"/api/prefix{version:V[0-9]}_{name:[a-z]+}_sufix/files",
+		"/api/prefix{version:V[0-9]}_{name:[a-z]+}_sufix/data",
+		"/api/prefix/files",
+		"/prefix{name:[a-z]+}suffix/data",
+		"/prefix{name:[a-z]+}/data",
+		"/api/{file}.json",
+		"/hello/{a}/{b}/{c}",
+		"/v1/foo/{id}/{pageSize}/{page}",
+		"/v1/foo/{id}/{pageSize}",
+		"/v1/foo/{id}",
+		"/v4/{id:^[1-9]\\d*}/click",
+	}
+	for _, opts := range [][]Option{nil, {WithoutBacktracking()}} {
+		tree := NewRouter[string](opts...)
+		for _, route := range routes {
+			if err := tree.Set(route, route); err != nil {
+				t.Fatalf("Set(%q): %v", route, err)
+			}
+		}
+		checkRequests(t, tree, testRequests{
+			{"/users/atreugo", false, "/users/{name}", map[string]string{"name": "atreugo"}},
+			{"/users", false, "/users", nil},
+			{"/user", true, "", nil},
+			{"/users/atreugo/jobs", false, "/users/{name}/jobs", map[string]string{"name": "atreugo"}},
+			{"/users/admin", false, "/users/admin", nil},
+			{"/users/active/proc", false, "/users/{name}/proc", map[string]string{"name": "active"}},
+			{"/static/assets/js/main.js", false, "/static/{filepath:*}", map[string]string{"filepath": "assets/js/main.js"}},
+			{"/data/orders/", true, "", nil},
+			{"/api/prefixV1_atreugo_sufix/files", false, "/api/prefix{version:V[0-9]}_{name:[a-z]+}_sufix/files", map[string]string{"version": "V1", "name": "atreugo"}},
+			{"/api/prefixV1_atreugo_sufix/data", false, "/api/prefix{version:V[0-9]}_{name:[a-z]+}_sufix/data", map[string]string{"version": "V1", "name": "atreugo"}},
+			// both match, the regex tries its longest match first
+			{"/prefixatreugosuffix/data", false, "/prefix{name:[a-z]+}/data", map[string]string{"name": "atreugosuffix"}},
+			{"/prefixatreugo/data", false, "/prefix{name:[a-z]+}/data", map[string]string{"name": "atreugo"}},
+			{"/api/name.json", false, "/api/{file}.json", map[string]string{"file": "name"}},
+			{"/api/prefixV1_1111_sufix/fake", true, "", nil},
+			{"/hello/a", true, "", nil},
+			{"/v1/foo/1/20/4", false, "/v1/foo/{id}/{pageSize}/{page}", map[string]string{"id": "1", "pageSize": "20", "page": "4"}},
+			{"/v1/foo/2/3", false, "/v1/foo/{id}/{pageSize}", map[string]string{"id": "2", "pageSize": "3"}},
+			{"/v1/foo/v3", false, "/v1/foo/{id}", map[string]string{"id": "v3"}},
+			{"/v4/123/click", false, "/v4/{id:^[1-9]\\d*}/click", map[string]string{"id": "123"}},
+		})
+
+		tree.Set("/{filepath:*}", "/{filepath:*}")
+		checkRequests(t, tree, testRequests{
+			{"/js/main.js", false, "/{filepath:*}", map[string]string{"filepath": "js/main.js"}},
+			{"/hello/a", false, "/{filepath:*}", map[string]string{"filepath": "hello/a"}},
+		})
+	}
+}
+
 func TestTreeDuplicatePath(t *testing.T) {
 	tree := NewRouter[string]()
 
@@ -409,11 +1536,7 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 			wantErr:     true,
 			wantErrText: "path '/{static:*}' conflicts with existing wildcard or param '{filepath:*}'",
 		},
-		{
-			route:       "/static/{filepath:*}/other",
-			wantErr:     true,
-			wantErrText: "wildcard routes are only allowed at the end of the path in path '/static/{filepath:*}/other'",
-		},
+		{route: "/static/{filepath:*}/other", wantErr: false}, // backtracking allows wildcards in the middle
 		{
 			route:       "/{user}/",
 			wantErr:     true,
@@ -0,0 +1,309 @@
+package router
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// snapshotMagic starts every snapshot, followed by snapshotVersion, which is
+// bumped whenever the layout changes.
+const (
+	snapshotMagic   = "GRT\x00"
+	snapshotVersion = 2
+)
+
+// Codec encodes and decodes the values of a Router in snapshots.
+type Codec[T any] struct {
+	Encode func(T) ([]byte, error)
+	Decode func([]byte) (T, error)
+}
+
+// SetCodec sets the codec MarshalBinary and UnmarshalBinary use for the
+// values. Without one, the values must implement encoding.BinaryMarshaler
+// and their pointers encoding.BinaryUnmarshaler. It's not routine-safe.
+func (r *Router[T]) SetCodec(c Codec[T]) {
+	r.codec = c
+}
+
+func (r *Router[T]) encode(v T) ([]byte, error) {
+	if r.codec.Encode != nil {
+		return r.codec.Encode(v)
+	}
+	if m, ok := any(v).(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return nil, errors.New("no codec for the values")
+}
+
+func (r *Router[T]) decode(data []byte) (v T, err error) {
+	if r.codec.Decode != nil {
+		return r.codec.Decode(data)
+	}
+	if u, ok := any(&v).(encoding.BinaryUnmarshaler); ok {
+		return v, u.UnmarshalBinary(data)
+	}
+	return v, errors.New("no codec for the values")
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the options
+// and the built tree, which UnmarshalBinary loads without parsing the
+// patterns or sorting again. It's routine-safe.
+func (r *Router[T]) MarshalBinary() ([]byte, error) {
+	w := &snapshotWriter{buf: []byte(snapshotMagic)}
+	w.uvarint(snapshotVersion)
+	w.buf = append(w.buf, r.sep)
+	w.bool(r.noLeading)
+	w.bool(r.topic)
+	w.bool(r.fast)
+	w.bool(r.multi)
+	w.bool(r.flat != nil)
+	w.uvarint(uint64(r.seq))
+	if err := r.marshalNode(w, &r.tree); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+func (r *Router[T]) marshalNode(w *snapshotWriter, n *node[T]) error {
+	switch m := n.m.(type) {
+	case literal:
+		w.buf = append(w.buf, 0)
+		w.string(string(m))
+	case param:
+		w.buf = append(w.buf, 1)
+		w.string(m.key)
+		w.string(m.after)
+		w.buf = append(w.buf, m.sep)
+		w.bool(m.greedy)
+	case regex:
+		w.buf = append(w.buf, 2)
+		w.string(m.key)
+		w.string(m.expr)
+		w.buf = append(w.buf, m.sep)
+		w.bool(m.multi)
+	case wildcard:
+		w.buf = append(w.buf, 3)
+		w.string(m.key)
+		w.bool(m.lazy)
+	case level:
+		w.buf = append(w.buf, 4)
+		w.buf = append(w.buf, m.sep)
+		w.bool(m.first)
+	case multilevel:
+		w.buf = append(w.buf, 5)
+		w.buf = append(w.buf, m.sep)
+		w.bool(m.first)
+		w.bool(m.parent)
+	}
+	w.bool(n.assigned)
+	if n.assigned {
+		value, err := r.encode(n.handler)
+		if err != nil {
+			return ErrSnapshot.With("encoding the value of '" + n.pattern + "': " + err.Error())
+		}
+		w.string(n.pattern)
+		w.string(n.name)
+		w.uvarint(uint64(n.seq))
+		w.varint(int64(n.priority))
+		w.string(string(value))
+	}
+	w.uvarint(uint64(len(n.children)))
+	for _, child := range n.children {
+		if err := r.marshalNode(w, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the
+// options and the routes with the ones of a snapshot made by MarshalBinary.
+// Snapshots of other versions are rejected with ErrSnapshot.
+// It's not routine-safe.
+func (r *Router[T]) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic) || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshot.With("not a snapshot")
+	}
+	rd := &snapshotReader{data: data[len(snapshotMagic):]}
+	if v := rd.uvarint(); rd.err == nil && v != snapshotVersion {
+		return ErrSnapshot.With("unsupported version " + strconv.FormatUint(v, 10))
+	}
+	var c config
+	c.sep = rd.byte()
+	c.noLeading, c.topic, c.fast, c.multi = rd.bool(), rd.bool(), rd.bool(), rd.bool()
+	frozen := rd.bool()
+	seq := int(rd.uvarint())
+	tree, err := r.unmarshalNode(rd)
+	if err != nil {
+		return err
+	}
+	if rd.err != nil {
+		return rd.err
+	}
+	if len(rd.data) != 0 {
+		return ErrSnapshot.With("trailing data")
+	}
+	if err := tree.validate("", true); err != nil {
+		return ErrSnapshot.With(err.Error())
+	}
+	r.config, r.seq, r.tree, r.flat = c, seq, *tree, nil
+	if frozen {
+		r.Freeze()
+	}
+	return nil
+}
+
+func (r *Router[T]) unmarshalNode(rd *snapshotReader) (*node[T], error) {
+	n := &node[T]{}
+	switch rd.byte() {
+	case 0:
+		l := literal(rd.string())
+		n.m = l
+		if l != "" {
+			n.b = l[0]
+		}
+	case 1:
+		n.m = param{key: rd.string(), after: rd.string(), sep: rd.byte(), greedy: rd.bool()}
+	case 2:
+		key, expr, sep, multi := rd.string(), rd.string(), rd.byte(), rd.bool()
+		re, err := newRegex(key, expr, sep, multi)
+		if err != nil && rd.err == nil {
+			return nil, ErrSnapshot.With(err.Error())
+		}
+		n.m = re
+	case 3:
+		n.m = wildcard{key: rd.string(), lazy: rd.bool()}
+	case 4:
+		n.m = level{sep: rd.byte(), first: rd.bool()}
+	case 5:
+		n.m = multilevel{sep: rd.byte(), first: rd.bool(), parent: rd.bool()}
+	default:
+		rd.fail()
+	}
+	if n.assigned = rd.bool(); n.assigned {
+		n.pattern, n.name = rd.string(), rd.string()
+		n.seq, n.priority = int(rd.uvarint()), int(rd.varint())
+		if value := rd.string(); rd.err == nil {
+			v, err := r.decode([]byte(value))
+			if err != nil {
+				return nil, ErrSnapshot.With("decoding the value of '" + n.pattern + "': " + err.Error())
+			}
+			n.handler = v
+		}
+	}
+	count := rd.uvarint()
+	if count > uint64(len(rd.data)) { // every node takes a few bytes
+		rd.fail()
+	}
+	if rd.err != nil {
+		return nil, rd.err
+	}
+	if count != 0 {
+		n.children = make([]*node[T], count)
+	}
+	for i := range n.children {
+		child, err := r.unmarshalNode(rd)
+		if err != nil {
+			return nil, err
+		}
+		n.children[i] = child
+	}
+	n.maxprio, n.minprio = n.prios()
+	n.reindex()
+	return n, nil
+}
+
+type snapshotWriter struct {
+	buf []byte
+}
+
+func (w *snapshotWriter) uvarint(x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutUvarint(b[:], x)]...)
+}
+
+func (w *snapshotWriter) varint(x int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutVarint(b[:], x)]...)
+}
+
+func (w *snapshotWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *snapshotWriter) bool(b bool) {
+	if b {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+// snapshotReader reads what snapshotWriter wrote, returning zero values once
+// the data turned out truncated or corrupted, which err records.
+type snapshotReader struct {
+	data []byte
+	err  error
+}
+
+func (rd *snapshotReader) fail() {
+	if rd.err == nil {
+		rd.err = ErrSnapshot.With("truncated or corrupted")
+	}
+	rd.data = nil
+}
+
+func (rd *snapshotReader) uvarint() uint64 {
+	x, n := binary.Uvarint(rd.data)
+	if n <= 0 {
+		rd.fail()
+		return 0
+	}
+	rd.data = rd.data[n:]
+	return x
+}
+
+func (rd *snapshotReader) varint() int64 {
+	x, n := binary.Varint(rd.data)
+	if n <= 0 {
+		rd.fail()
+		return 0
+	}
+	rd.data = rd.data[n:]
+	return x
+}
+
+func (rd *snapshotReader) byte() byte {
+	if len(rd.data) == 0 {
+		rd.fail()
+		return 0
+	}
+	b := rd.data[0]
+	rd.data = rd.data[1:]
+	return b
+}
+
+func (rd *snapshotReader) bool() bool {
+	switch rd.byte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	rd.fail()
+	return false
+}
+
+func (rd *snapshotReader) string() string {
+	l := rd.uvarint()
+	if l > uint64(len(rd.data)) {
+		rd.fail()
+		return ""
+	}
+	s := string(rd.data[:l])
+	rd.data = rd.data[l:]
+	return s
+}
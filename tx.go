@@ -0,0 +1,84 @@
+package router
+
+// Tx gathers changes to a Router, applied all together by Commit.
+type Tx[T any] struct {
+	r   *Router[T]
+	ops []txOp[T]
+}
+
+type txOp[T any] struct {
+	route  Route[T]
+	delete bool
+}
+
+// Begin starts a transaction on the router.
+func (r *Router[T]) Begin() *Tx[T] {
+	return &Tx[T]{r: r}
+}
+
+// Set registers a value for the given URL pattern on Commit.
+func (tx *Tx[T]) Set(path string, value T) {
+	tx.SetRoute(Route[T]{Pattern: path, Value: value})
+}
+
+// SetRoute registers a route on Commit.
+func (tx *Tx[T]) SetRoute(route Route[T]) {
+	tx.ops = append(tx.ops, txOp[T]{route: route})
+}
+
+// Delete unregisters the given URL pattern on Commit, as registered.
+func (tx *Tx[T]) Delete(path string) {
+	tx.ops = append(tx.ops, txOp[T]{route: Route[T]{Pattern: path}, delete: true})
+}
+
+// Commit applies the changes in order, either all of them or none if any
+// fails, returning the errors of all the failing ones as Errors. The changes
+// are made in place, those already made being undone on failure. The
+// transaction is empty afterwards. It's not routine-safe.
+func (tx *Tx[T]) Commit() error {
+	r, ops := tx.r, tx.ops
+	tx.ops = nil
+	if r.flat != nil && len(ops) != 0 {
+		return Errors{ErrFrozen.With(ops[0].route.Pattern)}
+	}
+	var errs Errors
+	var undo []*node[T] // copies of the removed nodes, unassigned for the added ones
+	seq := r.seq
+	for _, op := range ops {
+		if !op.delete {
+			if err := r.set(op.route); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			undo = append(undo, &node[T]{pattern: op.route.Pattern})
+			continue
+		}
+		removed := r.tree.remove(op.route.Pattern, &r.config)
+		if removed == nil {
+			errs = append(errs, ErrNotFound.With(op.route.Pattern))
+			continue
+		}
+		undo = append(undo, removed)
+	}
+	if len(errs) != 0 {
+		for i := len(undo) - 1; i >= 0; i-- {
+			old := undo[i]
+			if !old.assigned {
+				r.tree.remove(old.pattern, &r.config)
+				continue
+			}
+			n, err := r.tree.add(old.pattern, old.handler, old.priority, &r.config)
+			if err != nil {
+				panic(err) // it was there before
+			}
+			n.name, n.seq = old.name, old.seq
+		}
+		r.seq = seq
+	}
+	r.tree.sort()
+	r.mutated()
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
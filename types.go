@@ -11,6 +11,18 @@ type matcher interface {
 	string() string
 }
 
+// retrier is implemented by matchers able to match several prefixes of a path.
+type retrier interface {
+	// retry returns the prefix to try after path[:end], in order of preference.
+	retry(s string, end int) (int, bool)
+}
+
+// capturer is implemented by matchers yielding more params than their key.
+type capturer interface {
+	// capture calls f with the extra params of the matched string.
+	capture(s string, f func(key, value string))
+}
+
 type literal string
 
 func (l literal) match(s string) (int, string, bool) {
@@ -28,40 +40,121 @@ func (l literal) string() string {
 	return string(l)
 }
 
+// param matches up to the separator, or up to an occurrence of the suffix
+// within the segment, the first one unless greedy.
 type param struct {
-	key   string
-	after string
+	key    string
+	after  string
+	sep    byte
+	greedy bool
 }
 
 func (p param) match(s string) (int, string, bool) {
 	if s == "" {
 		return 0, "", false
 	}
-	i := strings.IndexByte(s, '/')
+	i := strings.IndexByte(s, p.sep)
 	if i == -1 {
 		i = len(s)
 	}
 	if p.after != "" {
-		i = strings.Index(s[:i], p.after)
+		i, ok := p.retry(s, 0)
+		return i, p.key, ok
 	}
-	return i, p.key, i != -1
+	return i, p.key, true
+}
+
+// retry finds the next occurrence of the suffix in the segment, leaving
+// at least one byte to the param. Starting from end 0, it finds the first
+// occurrence, or the last one if greedy.
+func (p param) retry(s string, end int) (int, bool) {
+	if p.after == "" {
+		return 0, false
+	}
+	i := strings.IndexByte(s, p.sep)
+	if i == -1 {
+		i = len(s)
+	}
+	if p.greedy {
+		if end != 0 {
+			i = end - 1 + len(p.after)
+		}
+		if i < 1 {
+			return 0, false
+		}
+		j := strings.LastIndex(s[1:i], p.after)
+		return 1 + j, j != -1
+	}
+	if end+1 > i {
+		return 0, false
+	}
+	j := strings.Index(s[end+1:i], p.after)
+	return end + 1 + j, j != -1
 }
 
 func (p param) equal(m matcher) bool {
 	if m2, ok := m.(param); ok {
-		return p.key == m2.key && p.after == m2.after
+		return p == m2
 	}
 	return false
 }
 
 func (p param) string() string {
+	if p.greedy {
+		return "{" + p.key + "+}" + p.after
+	}
 	return "{" + p.key + "}" + p.after
 }
 
-type wildcard string
+// wildcard matches the longest prefix of the path that lets the rest of the
+// pattern match, or the shortest one if lazy.
+type wildcard struct {
+	key  string
+	lazy bool
+}
 
 func (w wildcard) match(s string) (int, string, bool) {
-	return len(s), string(w), true
+	if w.lazy {
+		return 0, w.key, true
+	}
+	return len(s), w.key, true
+}
+
+func (w wildcard) retry(s string, end int) (int, bool) {
+	if w.lazy {
+		return end + 1, end < len(s)
+	}
+	return end - 1, end > 0
+}
+
+// next returns the next end after end, in the order of retry, where the
+// literal l follows, or -1.
+func (w wildcard) next(s string, end int, l string) int {
+	if w.lazy {
+		if end >= len(s) {
+			return -1
+		}
+		if i := strings.Index(s[end+1:], l); i != -1 {
+			return end + 1 + i
+		}
+		return -1
+	}
+	if end == 0 {
+		return -1
+	}
+	limit := end - 1 + len(l)
+	if limit > len(s) {
+		limit = len(s)
+	}
+	return strings.LastIndex(s[:limit], l)
+}
+
+// closer returns whichever of the ends retry reaches first, -1 being none.
+func (w wildcard) closer(a, b int) int {
+	if a == -1 || b != -1 && (b < a) == w.lazy {
+		return b
+	}
+	return a
 }
 
 func (w wildcard) equal(m matcher) bool {
@@ -72,35 +165,153 @@ func (w wildcard) equal(m matcher) bool {
 }
 
 func (w wildcard) string() string {
-	return "{" + string(w) + ":*}"
+	if w.lazy {
+		return "{" + w.key + ":*?}"
+	}
+	return "{" + w.key + ":*}"
+}
+
+// level matches a single topic level, as '+' in MQTT topic filters.
+type level struct {
+	sep   byte
+	first bool // at the beginning of the filter, topics starting with '$' are excluded
+}
+
+func (l level) match(s string) (int, string, bool) {
+	if l.first && s != "" && s[0] == '$' {
+		return 0, "", false
+	}
+	i := strings.IndexByte(s, l.sep)
+	if i == -1 {
+		i = len(s)
+	}
+	return i, "", true
 }
 
+func (l level) equal(m matcher) bool {
+	if m2, ok := m.(level); ok {
+		return l == m2
+	}
+	return false
+}
+
+func (l level) string() string {
+	return "+"
+}
+
+// multilevel matches the remaining topic levels, as '#' in MQTT topic filters.
+type multilevel struct {
+	sep    byte
+	first  bool // at the beginning of the filter, topics starting with '$' are excluded
+	parent bool // the separator before '#' is included, so the parent level matches too
+}
+
+func (w multilevel) match(s string) (int, string, bool) {
+	if w.first && s != "" && s[0] == '$' {
+		return 0, "", false
+	}
+	if w.parent && s != "" && s[0] != w.sep {
+		return 0, "", false
+	}
+	return len(s), "", true
+}
+
+func (w multilevel) equal(m matcher) bool {
+	if m2, ok := m.(multilevel); ok {
+		return w == m2
+	}
+	return false
+}
+
+func (w multilevel) string() string {
+	if w.parent {
+		return string(w.sep) + "#"
+	}
+	return "#"
+}
+
+// regex matches a prefix of the segment, or of the path if multi.
 type regex struct {
-	key string
-	*regexp.Regexp
+	key   string
+	expr  string         // as written in the pattern
+	re    *regexp.Regexp // anchored at the start
+	full  *regexp.Regexp // anchored at both ends
+	sep   byte
+	multi bool
+}
+
+func (w regex) segment(s string) string {
+	if !w.multi {
+		if i := strings.IndexByte(s, w.sep); i != -1 {
+			return s[:i]
+		}
+	}
+	return s
 }
 
 func (w regex) match(s string) (int, string, bool) {
-	match := w.FindString(s)
-	return len(match), w.key, match != ""
+	end := len(w.re.FindString(w.segment(s)))
+	if end == 0 {
+		// a lazy expression prefers the empty match, take the longest instead
+		end, _ = w.retry(s, 0)
+	}
+	return end, w.key, end != 0
+}
+
+// retry tries the other non-empty prefixes matching the whole expression,
+// from the longest to the shortest.
+func (w regex) retry(s string, end int) (int, bool) {
+	s = w.segment(s)
+	first := len(w.re.FindString(s))
+	i := len(s)
+	if end != first {
+		i = end - 1
+	}
+	for ; i > 0; i-- {
+		if i != first && w.full.MatchString(s[:i]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// capture reports the named capture groups.
+func (w regex) capture(s string, f func(key, value string)) {
+	names := w.full.SubexpNames()
+	if len(names) == 1 {
+		return
+	}
+	m := w.full.FindStringSubmatchIndex(s)
+	for i := 1; i < len(names) && m != nil; i++ {
+		if names[i] != "" && m[2*i] >= 0 {
+			f(names[i], s[m[2*i]:m[2*i+1]])
+		}
+	}
 }
 
 func (w regex) equal(m matcher) bool {
 	if m2, ok := m.(regex); ok {
-		return w.String() == m2.String()
+		return w.key == m2.key && w.expr == m2.expr && w.sep == m2.sep && w.multi == m2.multi
 	}
 	return false
 }
 
 func (w regex) string() string {
-	return "{" + w.key + ":" + w.Regexp.String() + "}"
+	return "{" + w.key + ":" + w.expr + "}"
 }
 
 type node[T any] struct {
 	children []*node[T]
 	m        matcher
 	handler  T
-	lastlit  int // cnt literal children, for optimization
+	pattern  string
+	name     string
+	seq      int // registration order
+	priority int
+	maxprio  int          // highest priority in the subtree
+	minprio  int          // lowest priority in the subtree
+	lastlit  int          // last of the leading literal children, for optimization
+	index    *[256]uint16 // 1 + the leading literal child starting with a byte
 	assigned bool
 	b        byte // for optimization
 }
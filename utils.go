@@ -1,29 +1,25 @@
 package router
 
-import "unicode/utf8"
-
 func typeID(m matcher) int {
 	switch m.(type) {
 	case literal:
 		return 0
-	case param:
+	case param, level:
 		return 1
 	case regex:
 		return 2
-	case wildcard:
+	case wildcard, multilevel:
 		return 3
 	}
 	return -1
 }
 
+// lcp returns the length of the longest common prefix in bytes, so that
+// literal siblings never share their first byte, even in the middle of a rune.
 func lcp(a, b string) int {
-	for i, ra := range a {
-		if i >= len(b) {
-			return i
-		}
-		if rb, _ := utf8.DecodeRuneInString(b[i:]); ra != rb {
-			return i
-		}
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
-	return len(a)
+	return i
 }